@@ -0,0 +1,63 @@
+package gitgrab
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ArchivedDir is the directory below the target directory that archived
+// repositories are placed in when they are kept apart from active ones
+const ArchivedDir = "archived"
+
+// archivedBefore reports whether the repository was already archived when it
+// was last synced, so its final sync has happened
+func archivedBefore(config CloneConfig) bool {
+	return config.Repository.Archived && config.Previous != nil && config.Previous.Archived
+}
+
+// unarchived reports whether the repository was archived when it was last
+// synced and has been unarchived since
+func unarchived(config CloneConfig) bool {
+	return !config.Repository.Archived && config.Previous != nil && config.Previous.Archived
+}
+
+// freezeArchived makes the working tree of an archived repository read-only
+// after its final sync
+func freezeArchived(config CloneConfig, repoPath string) {
+	e := Event{Kind: EventArchived, Path: repoPath}
+	if err := setWritable(repoPath, false); err != nil {
+		e.Kind = EventWarning
+		e.Message = fmt.Sprintf("Could not make %s read-only: %v", config.Repository.Name, err)
+	}
+	config.report(e)
+}
+
+// setWritable adds or removes write permission on every file in a working
+// tree. The .git directory is left alone, so the repository itself stays
+// usable.
+func setWritable(repoPath string, writable bool) error {
+	return filepath.WalkDir(repoPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		mode := info.Mode().Perm()
+		if writable {
+			mode |= 0200
+		} else {
+			mode &^= 0222
+		}
+		return os.Chmod(path, mode)
+	})
+}
@@ -0,0 +1,70 @@
+package gitgrab
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSyncRepo_ArchivedRepository(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "upstream")
+	initRepoOnBranch(t, source, "main")
+	os.WriteFile(filepath.Join(source, "README.md"), []byte("hello"), 0644)
+	exec.Command("git", "-C", source, "add", "README.md").Run()
+	exec.Command("git", "-C", source, "-c", "user.email=test@example.com", "-c", "user.name=Test User",
+		"commit", "-m", "Add README").Run()
+
+	targetDir := t.TempDir()
+	state, _ := LoadState(targetDir)
+	var kinds []EventKind
+	config := CloneConfig{
+		Repository: Repository{ID: 7, Name: "upstream", FullName: "acme/upstream", CloneURL: HTTPURL(source), DefaultBranch: "main"},
+		TargetDir:  targetDir,
+		Method:     CloneMethodHTTP,
+		Reporter:   ReporterFunc(func(e Event) { kinds = append(kinds, e.Kind) }),
+	}
+	syncOnce := func() SyncResult {
+		t.Helper()
+		kinds = nil
+		config.Previous = state.Previous(config.Repository)
+		result := SyncRepo(config)
+		if result.Err != nil {
+			t.Fatalf("Unexpected error: %v", result.Err)
+		}
+		state.Record(result)
+		return result
+	}
+	readme := filepath.Join(targetDir, "upstream", "README.md")
+	writable := func() bool {
+		info, err := os.Stat(readme)
+		if err != nil {
+			t.Fatalf("Failed to stat README: %v", err)
+		}
+		return info.Mode().Perm()&0200 != 0
+	}
+
+	syncOnce()
+	if !writable() {
+		t.Error("Expected the working tree of an active repository to be writable")
+	}
+
+	// The first sync after archiving still updates the clone
+	config.Repository.Archived = true
+	if result := syncOnce(); result.Action != ActionPull || !slices.Contains(kinds, EventArchived) {
+		t.Errorf("Expected a final pull, got %+v with events %v", result, kinds)
+	}
+	if writable() {
+		t.Error("Expected the working tree to be read-only after the final sync")
+	}
+
+	if result := syncOnce(); result.Action != ActionSkip || result.Reason != "archived upstream" {
+		t.Errorf("Expected later syncs to be skipped, got %+v", result)
+	}
+
+	config.Repository.Archived = false
+	if result := syncOnce(); result.Action != ActionPull || !writable() {
+		t.Errorf("Expected an unarchived repository to be pulled and writable again, got %+v", result)
+	}
+}
@@ -0,0 +1,151 @@
+package gitgrab
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// branchFormat is the for-each-ref format listing local branches: whether
+// the branch is checked out, its ref, commit and upstream, where it stands
+// against the upstream ("<" behind, ">" ahead, "<>" diverged, "=" up to
+// date) and the work tree it is checked out in, if any
+const branchFormat = "%(HEAD)%09%(refname)%09%(objectname)%09%(upstream)%09%(upstream:trackshort)%09%(worktreepath)"
+
+// localBranch is a local branch as listed with branchFormat
+type localBranch struct {
+	Current  bool
+	Ref      string
+	Commit   string
+	Upstream string
+	Track    string
+	// Worktree is the work tree the branch is checked out in, which may be
+	// a linked work tree rather than the repository's own
+	Worktree string
+}
+
+// Name returns the branch name without the refs/heads/ prefix
+func (b localBranch) Name() string {
+	return strings.TrimPrefix(b.Ref, "refs/heads/")
+}
+
+// listBranches returns the local branches of a repository
+func listBranches(ctx context.Context, git GitRunner, repoPath string) ([]localBranch, error) {
+	output, err := git.Run(ctx, repoPath, nil, "for-each-ref", "--format="+branchFormat, "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []localBranch
+	// The first field is blank for branches that are not checked out, so
+	// only line ends may be trimmed
+	for _, line := range strings.Split(strings.TrimRight(output, "\n"), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 6 {
+			continue
+		}
+		branches = append(branches, localBranch{
+			Current:  fields[0] == "*",
+			Ref:      fields[1],
+			Commit:   fields[2],
+			Upstream: fields[3],
+			Track:    fields[4],
+			Worktree: fields[5],
+		})
+	}
+	return branches, nil
+}
+
+// updateBranches fast-forwards every local branch that is behind its
+// upstream, without checking it out, and lists the branches that have
+// diverged from their upstream. The checked out branch is left to the pull,
+// and branches checked out in linked work trees are left alone, since moving
+// them would change what those work trees show as staged.
+func updateBranches(ctx context.Context, config CloneConfig, repoPath string) (updated, diverged []string) {
+	event := func(kind EventKind, branch string) Event {
+		return Event{Kind: kind, Path: repoPath, Action: ActionFetch, Branch: branch}
+	}
+
+	branches, err := listBranches(ctx, config.git(), repoPath)
+	if err != nil {
+		e := event(EventWarning, "")
+		e.Message = fmt.Sprintf("Could not list branches of %s: %v", config.Repository.Name, err)
+		config.report(e)
+		return nil, nil
+	}
+
+	for _, branch := range branches {
+		if branch.Current || branch.Worktree != "" || branch.Upstream == "" {
+			continue
+		}
+		switch branch.Track {
+		case "<":
+			// The old commit makes the update fail if the branch moved
+			// since it was listed
+			if _, err := config.git().Run(ctx, repoPath, nil, "update-ref", branch.Ref, branch.Upstream, branch.Commit); err != nil {
+				e := event(EventWarning, branch.Name())
+				e.Message = fmt.Sprintf("Could not fast-forward branch %s: %v", branch.Name(), err)
+				config.report(e)
+				continue
+			}
+			updated = append(updated, branch.Name())
+			config.report(event(EventBranchUpdated, branch.Name()))
+		case "<>":
+			diverged = append(diverged, branch.Name())
+			e := event(EventBranchDiverged, branch.Name())
+			e.Message = strings.TrimPrefix(branch.Upstream, "refs/remotes/")
+			config.report(e)
+		}
+	}
+	return updated, diverged
+}
+
+// remoteDefaultBranch returns the default branch of origin as last recorded
+// locally in origin/HEAD, or an empty string when it is not known
+func remoteDefaultBranch(ctx context.Context, git GitRunner, repoPath string) string {
+	output, err := git.Run(ctx, repoPath, nil, "symbolic-ref", "--quiet", "refs/remotes/origin/HEAD")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.TrimSpace(output), "refs/remotes/origin/")
+}
+
+// branchExists reports whether a local branch exists
+func branchExists(ctx context.Context, git GitRunner, repoPath, branch string) bool {
+	_, err := git.Run(ctx, repoPath, nil, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// remoteBranchExists reports whether origin had a branch when last fetched
+func remoteBranchExists(ctx context.Context, git GitRunner, repoPath, branch string) bool {
+	_, err := git.Run(ctx, repoPath, nil, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch)
+	return err == nil
+}
+
+// migrateDefaultBranch follows a change of the default branch upstream and
+// points origin/HEAD at the new one. Only when the old branch is gone from
+// origin was it renamed: the local branch of the old name is then renamed
+// too, unless a branch of the new name exists already, and made to track
+// the new upstream branch. A default branch that merely moved leaves the
+// local branches alone. It reports whether the branch was renamed.
+func migrateDefaultBranch(ctx context.Context, config CloneConfig, repoPath, previous, current string) (bool, error) {
+	git := config.git()
+	if _, err := runGit(ctx, config, repoPath, "fetch", "--prune"); err != nil {
+		return false, err
+	}
+	renamed := !remoteBranchExists(ctx, git, repoPath, previous)
+	if renamed {
+		if branchExists(ctx, git, repoPath, previous) && !branchExists(ctx, git, repoPath, current) {
+			if _, err := git.Run(ctx, repoPath, nil, "branch", "-m", previous, current); err != nil {
+				return false, err
+			}
+		}
+		if branchExists(ctx, git, repoPath, current) {
+			if _, err := git.Run(ctx, repoPath, nil, "branch", "--set-upstream-to=origin/"+current, current); err != nil {
+				return true, err
+			}
+		}
+	}
+	_, err := git.Run(ctx, repoPath, nil, "remote", "set-head", "origin", current)
+	return renamed, err
+}
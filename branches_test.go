@@ -0,0 +1,215 @@
+package gitgrab
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSyncRepo_AllBranches(t *testing.T) {
+	for backend, runner := range map[string]GitRunner{BackendGit: ExecGitRunner{}, BackendGoGit: GoGitRunner{}} {
+		t.Run(backend, func(t *testing.T) {
+			source := filepath.Join(t.TempDir(), "upstream")
+			initRepoOnBranch(t, source, "main")
+			commit := func(dir, branch, message string) {
+				exec.Command("git", "-C", dir, "checkout", "-q", branch).Run()
+				exec.Command("git", "-C", dir, "-c", "user.email=test@example.com", "-c", "user.name=Test User",
+					"commit", "--allow-empty", "-m", message).Run()
+			}
+			for _, branch := range []string{"feature", "wip"} {
+				exec.Command("git", "-C", source, "branch", branch).Run()
+			}
+
+			config := CloneConfig{
+				Repository:  Repository{Name: "upstream", CloneURL: HTTPURL(source), DefaultBranch: "main"},
+				TargetDir:   t.TempDir(),
+				Method:      CloneMethodHTTP,
+				Reporter:    ReporterFunc(func(Event) {}),
+				Git:         runner,
+				AllBranches: true,
+			}
+			if result := SyncRepo(config); result.Err != nil {
+				t.Fatalf("Unexpected error cloning: %v", result.Err)
+			}
+			clone := config.RepoPath()
+			for _, branch := range []string{"feature", "wip"} {
+				exec.Command("git", "-C", clone, "branch", "--track", branch, "origin/"+branch).Run()
+			}
+
+			commit(source, "feature", "Upstream feature work")
+			commit(source, "wip", "Upstream wip work")
+			commit(clone, "wip", "Local wip work")
+			exec.Command("git", "-C", clone, "checkout", "-q", "main").Run()
+
+			result := SyncRepo(config)
+
+			if result.Err != nil {
+				t.Fatalf("Unexpected error: %v", result.Err)
+			}
+			if !reflect.DeepEqual(result.UpdatedBranches, []string{"feature"}) {
+				t.Errorf("Expected feature to be fast-forwarded, got %v", result.UpdatedBranches)
+			}
+			if !reflect.DeepEqual(result.DivergedBranches, []string{"wip"}) {
+				t.Errorf("Expected wip to be reported as diverged, got %v", result.DivergedBranches)
+			}
+			if local, upstream := revParse(t, clone, "feature"), revParse(t, clone, "origin/feature"); local != upstream {
+				t.Errorf("Expected feature at %s, got %s", upstream, local)
+			}
+		})
+	}
+}
+
+func TestSyncRepo_AllBranchesLeavesLinkedWorktrees(t *testing.T) {
+	for backend, runner := range map[string]GitRunner{BackendGit: ExecGitRunner{}, BackendGoGit: GoGitRunner{}} {
+		t.Run(backend, func(t *testing.T) {
+			source := filepath.Join(t.TempDir(), "upstream")
+			initRepoOnBranch(t, source, "main")
+			exec.Command("git", "-C", source, "branch", "feature").Run()
+
+			config := CloneConfig{
+				Repository:  Repository{Name: "upstream", CloneURL: HTTPURL(source), DefaultBranch: "main"},
+				TargetDir:   t.TempDir(),
+				Method:      CloneMethodHTTP,
+				Reporter:    ReporterFunc(func(Event) {}),
+				Git:         runner,
+				AllBranches: true,
+			}
+			if result := SyncRepo(config); result.Err != nil {
+				t.Fatalf("Unexpected error cloning: %v", result.Err)
+			}
+			clone := config.RepoPath()
+			worktree := filepath.Join(t.TempDir(), "feature")
+			exec.Command("git", "-C", clone, "branch", "--track", "feature", "origin/feature").Run()
+			if err := exec.Command("git", "-C", clone, "worktree", "add", "-q", worktree, "feature").Run(); err != nil {
+				t.Fatalf("Failed to add work tree: %v", err)
+			}
+			before := revParse(t, clone, "feature")
+
+			exec.Command("git", "-C", source, "checkout", "-q", "feature").Run()
+			os.WriteFile(filepath.Join(source, "f.txt"), []byte("upstream\n"), 0644)
+			exec.Command("git", "-C", source, "add", "f.txt").Run()
+			exec.Command("git", "-C", source, "-c", "user.email=test@example.com", "-c", "user.name=Test User",
+				"commit", "-q", "-m", "Upstream feature work").Run()
+
+			result := SyncRepo(config)
+
+			if result.Err != nil {
+				t.Fatalf("Unexpected error: %v", result.Err)
+			}
+			if len(result.UpdatedBranches) != 0 {
+				t.Errorf("Expected no branches to be fast-forwarded, got %v", result.UpdatedBranches)
+			}
+			if after := revParse(t, clone, "feature"); after != before {
+				t.Errorf("Expected feature to stay at %s, got %s", before, after)
+			}
+			if status, _ := exec.Command("git", "-C", worktree, "status", "--porcelain").Output(); len(status) != 0 {
+				t.Errorf("Expected a clean work tree, got %q", status)
+			}
+		})
+	}
+}
+
+func TestSyncRepo_MigratesRenamedDefaultBranch(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "upstream")
+	initRepoOnBranch(t, source, "master")
+
+	config := CloneConfig{
+		Repository: Repository{Name: "upstream", CloneURL: HTTPURL(source), DefaultBranch: "master"},
+		TargetDir:  t.TempDir(),
+		Method:     CloneMethodHTTP,
+		Reporter:   ReporterFunc(func(Event) {}),
+	}
+	if result := SyncRepo(config); result.Err != nil {
+		t.Fatalf("Unexpected error cloning: %v", result.Err)
+	}
+
+	exec.Command("git", "-C", source, "branch", "-m", "master", "main").Run()
+	config.Repository.DefaultBranch = "main"
+
+	var kinds []EventKind
+	config.Reporter = ReporterFunc(func(e Event) { kinds = append(kinds, e.Kind) })
+	result := SyncRepo(config)
+
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+	if result.Action != ActionPull || result.RenamedFrom != "master" {
+		t.Errorf("Expected a pull after migrating from master, got %+v", result)
+	}
+	if !slices.Contains(kinds, EventBranchRenamed) {
+		t.Errorf("Expected the migration to be reported, got %v", kinds)
+	}
+
+	clone := config.RepoPath()
+	for command, expected := range map[string]string{
+		"branch --show-current":                         "main",
+		"rev-parse --abbrev-ref main@{upstream}":        "origin/main",
+		"symbolic-ref --short refs/remotes/origin/HEAD": "origin/main",
+	} {
+		output, err := exec.Command("git", append([]string{"-C", clone}, strings.Fields(command)...)...).Output()
+		if actual := strings.TrimSpace(string(output)); err != nil || actual != expected {
+			t.Errorf("Expected git %s to give %s, got %q (%v)", command, expected, actual, err)
+		}
+	}
+
+	// The migration happens once
+	if again := SyncRepo(config); again.Err != nil || again.RenamedFrom != "" {
+		t.Errorf("Expected no further migration, got %+v", again)
+	}
+}
+
+func TestSyncRepo_KeepsOldDefaultBranchStillUpstream(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "upstream")
+	initRepoOnBranch(t, source, "master")
+
+	config := CloneConfig{
+		Repository: Repository{Name: "upstream", CloneURL: HTTPURL(source), DefaultBranch: "master"},
+		TargetDir:  t.TempDir(),
+		Method:     CloneMethodHTTP,
+		Reporter:   ReporterFunc(func(Event) {}),
+	}
+	if result := SyncRepo(config); result.Err != nil {
+		t.Fatalf("Unexpected error cloning: %v", result.Err)
+	}
+	clone := config.RepoPath()
+	commit := func(dir, message string) {
+		exec.Command("git", "-C", dir, "-c", "user.email=test@example.com", "-c", "user.name=Test User",
+			"commit", "--allow-empty", "-m", message).Run()
+	}
+	commit(clone, "Local work")
+	local := revParse(t, clone, "master")
+
+	// Upstream keeps master and makes a new main branch the default
+	exec.Command("git", "-C", source, "checkout", "-q", "-b", "main").Run()
+	commit(source, "Work on main")
+	config.Repository.DefaultBranch = "main"
+
+	var kinds []EventKind
+	config.Reporter = ReporterFunc(func(e Event) { kinds = append(kinds, e.Kind) })
+	result := SyncRepo(config)
+
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+	if result.Action != ActionFetch || result.RenamedFrom != "" {
+		t.Errorf("Expected a fetch without migration, got %+v", result)
+	}
+	if !slices.Contains(kinds, EventDefaultBranchChanged) || slices.Contains(kinds, EventBranchRenamed) {
+		t.Errorf("Expected the default branch change to be reported, got %v", kinds)
+	}
+	for command, expected := range map[string]string{
+		"branch --show-current":                         "master",
+		"rev-parse master":                              local,
+		"rev-parse --abbrev-ref master@{upstream}":      "origin/master",
+		"symbolic-ref --short refs/remotes/origin/HEAD": "origin/main",
+	} {
+		output, err := exec.Command("git", append([]string{"-C", clone}, strings.Fields(command)...)...).Output()
+		if actual := strings.TrimSpace(string(output)); err != nil || actual != expected {
+			t.Errorf("Expected git %s to give %s, got %q (%v)", command, expected, actual, err)
+		}
+	}
+}
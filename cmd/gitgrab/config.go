@@ -0,0 +1,91 @@
+package main
+
+import (
+	"github.com/scottbrown/gitgrab"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configPath string
+	layout     string
+)
+
+// filterFlags are the flags that narrow the selection; when none of them is
+// given, the filters from the configuration file are used
+var filterFlags = []string{"include", "exclude", "no-forks", "no-archived", "topic", "language", "visibility"}
+
+// loadConfig reads the file given with --config, or the one discovered for
+// targetDir. It returns nil when there is no configuration file.
+func loadConfig(targetDir string) (*gitgrab.Config, error) {
+	path := configPath
+	if path == "" {
+		path = gitgrab.FindConfig(targetDir)
+	}
+	if path == "" {
+		return nil, nil
+	}
+	return gitgrab.LoadConfig(path)
+}
+
+// applyConfig takes settings from the configuration file for every flag that
+// was not given on the command line
+func applyConfig(cmd *cobra.Command, cfg *gitgrab.Config) {
+	changed := cmd.Flags().Changed
+
+	if cfg.Method != "" && !changed("method") {
+		cloneMethod = cfg.Method
+	}
+	if cfg.Jobs > 0 && !changed("jobs") {
+		jobs = cfg.Jobs
+	}
+	if cfg.Layout != "" && !changed("layout") {
+		layout = cfg.Layout
+	}
+	if cfg.Host != "" && !changed("host") {
+		hostName = cfg.Host
+	}
+	if cfg.APIURL != "" && !changed("api-url") {
+		apiURL = cfg.APIURL
+	}
+	if cfg.Backend != "" && !changed("backend") {
+		backend = cfg.Backend
+	}
+	if cfg.RemoteMismatch != "" && !changed("remote-mismatch") {
+		remoteMismatch = cfg.RemoteMismatch
+	}
+	if cfg.FixRemotes && !changed("fix-remotes") {
+		fixRemotes = true
+	}
+	if cfg.UpdateStrategy != "" && !changed("update-strategy") {
+		updateStrategy = cfg.UpdateStrategy
+	}
+	if cfg.AllBranches && !changed("all-branches") {
+		allBranches = true
+	}
+	if cfg.ArchivedDir && !changed("archived-dir") {
+		archivedDir = true
+	}
+	if cfg.Prune != "" && !changed("prune") {
+		prunePolicy = cfg.Prune
+	}
+
+	if !anyChanged(cmd, filterFlags...) {
+		includePatterns = cfg.Filters.Include
+		excludePatterns = cfg.Filters.Exclude
+		noForks = cfg.Filters.NoForks
+		noArchived = cfg.Filters.NoArchived
+		topics = cfg.Filters.Topics
+		languages = cfg.Filters.Languages
+		visibilities = cfg.Filters.Visibility
+	}
+}
+
+// anyChanged reports whether any of the named flags was set explicitly
+func anyChanged(cmd *cobra.Command, names ...string) bool {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
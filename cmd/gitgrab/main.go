@@ -1,27 +1,199 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/scottbrown/gitgrab"
 	"github.com/spf13/cobra"
 )
 
 var (
-	orgName     string
 	cloneMethod string
+	jobs        int
+	backend     string
+
+	remoteMismatch string
+	fixRemotes     bool
+	updateStrategy string
+	allBranches    bool
+	archivedDir    bool
+
+	maxRateLimitWait time.Duration
+	apiTimeout       time.Duration
+	repoTimeout      time.Duration
+
+	hostName string
+	apiURL   string
+
+	includePatterns []string
+	excludePatterns []string
+	noForks         bool
+	noArchived      bool
+	topics          []string
+	languages       []string
+	visibilities    []string
 )
 
+// buildFilter assembles the repository filter from the filter flags
+func buildFilter() (gitgrab.Filter, error) {
+	return gitgrab.FiltersConfig{
+		Include:    includePatterns,
+		Exclude:    excludePatterns,
+		NoForks:    noForks,
+		NoArchived: noArchived,
+		Topics:     topics,
+		Languages:  languages,
+		Visibility: visibilities,
+	}.Filter()
+}
+
+// resolveHost determines the git host and API endpoint from flags, falling
+// back to the GH_HOST and GITHUB_API_URL environment variables
+func resolveHost() (gitgrab.GitHost, string) {
+	host := gitgrab.GitHost(hostName)
+	if host == "" {
+		host = gitgrab.GitHost(os.Getenv("GH_HOST"))
+	}
+
+	baseURL := apiURL
+	if baseURL == "" && host.IsDefault() {
+		baseURL = os.Getenv("GITHUB_API_URL")
+	}
+	if baseURL == "" {
+		baseURL = host.APIBaseURL()
+	}
+
+	return host, baseURL
+}
+
+// info receives progress messages. It is stderr when stdout carries
+// machine-readable output.
+var info io.Writer = os.Stdout
+
+// newClient builds the API client with the rate limit settings from the flags
+func newClient(token gitgrab.GitHubToken, baseURL string) *gitgrab.GitHubClient {
+	return gitgrab.NewGitHubClient(token,
+		gitgrab.WithBaseURL(baseURL),
+		gitgrab.WithPageConcurrency(jobs),
+		gitgrab.WithMaxRateLimitWait(maxRateLimitWait),
+		gitgrab.WithRequestTimeout(apiTimeout),
+		gitgrab.WithRetryNotify(func(n gitgrab.RetryNotice) {
+			if n.RateLimited {
+				fmt.Fprintf(os.Stderr, "Warning: GitHub API rate limit hit, waiting %s before retrying...\n", n.Wait.Round(time.Second))
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: GitHub API returned %d, retrying in %s...\n", n.StatusCode, n.Wait.Round(time.Second))
+			}
+		}),
+	)
+}
+
+// candidate is a repository returned by a source, with the reason it is
+// skipped if it won't be synced
+type candidate struct {
+	config gitgrab.CloneConfig
+	skip   string
+}
+
+// collectRepositories lists the repositories of every source and prepares a
+// clone configuration for each, applying filters and per-repo overrides
+func collectRepositories(ctx context.Context, client *gitgrab.GitHubClient, sources []gitgrab.Source, cfg *gitgrab.Config, filter gitgrab.Filter, base gitgrab.CloneConfig) ([]candidate, error) {
+	seen := make(map[string]bool)
+
+	var candidates []candidate
+	for _, src := range sources {
+		fmt.Fprintf(info, "Fetching repositories for %s...\n", src)
+		repos, err := client.FetchSourceReposContext(ctx, src)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(repos) == 0 {
+			fmt.Fprintf(info, "No repositories found for %s\n", src)
+			continue
+		}
+
+		fmt.Fprintf(info, "Found %d repositories\n", len(repos))
+
+		selected := 0
+		for _, repo := range repos {
+			owner := repo.Owner.Login
+			if owner == "" {
+				owner = src.Name
+			}
+
+			// Sources may overlap, e.g. two teams sharing a repository
+			key := strings.ToLower(owner + "/" + repo.Name.String())
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			config := base
+			config.Repository = repo
+			config.Organization = gitgrab.OrganizationName(owner)
+			if archivedDir && repo.Archived {
+				config.TargetDir = filepath.Join(config.TargetDir, gitgrab.ArchivedDir)
+			}
+
+			if !filter.Match(repo) {
+				candidates = append(candidates, candidate{config: config, skip: "excluded by filters"})
+				continue
+			}
+			selected++
+
+			if cfg != nil {
+				var keep bool
+				if config, keep = cfg.Apply(config); !keep {
+					candidates = append(candidates, candidate{config: config, skip: "skipped by configuration"})
+					continue
+				}
+			}
+			candidates = append(candidates, candidate{config: config})
+		}
+
+		if selected != len(repos) {
+			fmt.Fprintf(info, "Selected %d repositories after filtering\n", selected)
+		}
+	}
+
+	// Each owner gets its own subdirectory with the owner layout, or with
+	// the auto layout when repositories of several owners are listed; a
+	// single user source may list repositories of many
+	template := gitgrab.LayoutTemplate(layout, countOwners(candidates))
+	for i := range candidates {
+		candidates[i].config.Layout = template
+	}
+
+	return candidates, nil
+}
+
+// countOwners returns the number of distinct owners of the repositories
+// listed
+func countOwners(candidates []candidate) int {
+	owners := make(map[string]bool)
+	for _, c := range candidates {
+		owners[strings.ToLower(c.config.Organization.String())] = true
+	}
+	return len(owners)
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "gitgrab [target_directory]",
-	Short: "Clone all repositories from a GitHub organization",
-	Long:  "GitGrab is a CLI utility that clones all GitHub repositories from a specified organization to a local directory.",
+	Short: "Clone all repositories from GitHub organizations and users",
+	Long:  "GitGrab is a CLI utility that clones all GitHub repositories from the specified organizations and users to a local directory.",
 	Args:  cobra.ExactArgs(1),
   Version: gitgrab.Version(),
 	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
 		targetDir := args[0]
 		token := os.Getenv("GITHUB_TOKEN")
 		
@@ -30,82 +202,256 @@ var rootCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Check if git is available
-		if _, err := exec.LookPath("git"); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: git is not installed or not in PATH\n")
+		switch outputFormat {
+		case outputText:
+		case outputJSON, outputNDJSON, outputJUnit:
+			info = os.Stderr
+		default:
+			fmt.Fprintf(os.Stderr, "Error: invalid output format: %s\n", outputFormat)
 			os.Exit(1)
 		}
+		if dryRun && outputFormat == outputJUnit {
+			fmt.Fprintf(os.Stderr, "Error: --output junit is not supported with --dry-run\n")
+			os.Exit(1)
+		}
+
+		// Create target directory if it doesn't exist; a dry run
+		// must not touch the disk
+		if !dryRun {
+			if err := os.MkdirAll(targetDir, 0755); err != nil {
+				fmt.Fprintf(os.Stderr, "Error creating directory %s: %v\n", targetDir, err)
+				os.Exit(1)
+			}
+		}
 
-		// Create target directory if it doesn't exist
-		if err := os.MkdirAll(targetDir, 0755); err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating directory %s: %v\n", targetDir, err)
+		cfg, err := loadConfig(targetDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+			os.Exit(1)
+		}
+		if cfg != nil {
+			applyConfig(cmd, cfg)
+			fmt.Fprintf(info, "Configuration file: %s\n", cfg.Path())
+		}
+
+		if err := gitgrab.ValidateLayout(layout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := gitgrab.ValidateRemoteMismatch(remoteMismatch); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := gitgrab.ValidateUpdateStrategy(updateStrategy, backend); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := gitgrab.ValidatePrune(prunePolicy); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
-		fmt.Printf("Fetching repositories for %s organization...\n", orgName)
-		fmt.Printf("Target directory: %s\n", targetDir)
-		fmt.Println(strings.Repeat("-", 50))
+		runner, err := gitgrab.NewGitRunner(backend)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Check if git is available; the go-git backend doesn't need it
+		if _, ok := runner.(gitgrab.ExecGitRunner); ok {
+			if _, err := exec.LookPath("git"); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: git is not installed or not in PATH\n")
+				os.Exit(1)
+			}
+		}
+
+		fmt.Fprintf(info, "Target directory: %s\n", targetDir)
+		fmt.Fprintln(info, strings.Repeat("-", 50))
 
 		// Parse clone method
 		method, err := gitgrab.ParseCloneMethod(cloneMethod)
 		if err != nil {
-			fmt.Printf("Warning: %v\n", err)
+			fmt.Fprintf(info, "Warning: %v\n", err)
+		}
+
+		filter, err := buildFilter()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
 
 		// Create typed values
 		githubToken := gitgrab.GitHubToken(token)
-		organization := gitgrab.OrganizationName(orgName)
+		host, baseURL := resolveHost()
+
+		client := newClient(githubToken, baseURL)
+		sources, err := buildSources(cmd, client, cfg)
+		if ctx.Err() != nil {
+			os.Exit(exitInterrupted)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 
-		client := gitgrab.NewGitHubClient(githubToken)
-		repos, err := client.FetchAllRepos(organization)
+		candidates, err := collectRepositories(ctx, client, sources, cfg, filter, gitgrab.CloneConfig{
+			TargetDir:      targetDir,
+			Token:          githubToken,
+			Method:         method,
+			Host:           host,
+			Git:            runner,
+			RemoteMismatch: remoteMismatch,
+			FixRemotes:     fixRemotes,
+			UpdateStrategy: updateStrategy,
+			AllBranches:    allBranches,
+		})
+		if ctx.Err() != nil {
+			os.Exit(exitInterrupted)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error fetching repositories: %v\n", err)
 			os.Exit(1)
 		}
 
-		if len(repos) == 0 {
-			fmt.Printf("No repositories found for %s organization\n", orgName)
-			return
+		// Repositories renamed or transferred since the last sync are found
+		// again by ID
+		state, err := gitgrab.LoadState(targetDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading state: %v\n", err)
+			os.Exit(1)
+		}
+		for i := range candidates {
+			candidates[i].config.Previous = state.Previous(candidates[i].config.Repository)
 		}
 
-		fmt.Printf("Found %d repositories\n\n", len(repos))
+		if rl := client.RateLimit(); rl.IsKnown() {
+			fmt.Fprintf(info, "GitHub API rate limit: %s\n", rl)
+		}
+		fmt.Fprintln(info)
 
-		successCount := 0
-		failureCount := 0
+		if dryRun {
+			// Repositories sharing a directory are not synced, as the
+			// Syncer refuses them
+			var configs []gitgrab.CloneConfig
+			for _, c := range candidates {
+				if c.skip == "" {
+					configs = append(configs, c.config)
+				}
+			}
+			conflicts := gitgrab.PathConflicts(configs)
 
-		for i, repo := range repos {
-			fmt.Printf("[%d/%d] Cloning %s...\n", i+1, len(repos), repo.Name)
-			
-			config := gitgrab.CloneConfig{
-				Repository:   repo,
-				TargetDir:    targetDir,
-				Token:        githubToken,
-				Organization: organization,
-				Method:       method,
+			entries := make([]gitgrab.PlanEntry, 0, len(candidates))
+			for _, c := range candidates {
+				if c.skip != "" {
+					entries = append(entries, gitgrab.SkipEntry(c.config, c.skip))
+					continue
+				}
+				if err := conflicts[0]; err != nil {
+					entries = append(entries, gitgrab.SkipEntry(c.config, err.Error()))
+				} else {
+					entries = append(entries, gitgrab.PlanRepo(c.config))
+				}
+				conflicts = conflicts[1:]
 			}
-			
-			if err := gitgrab.CloneRepo(config); err != nil {
-				fmt.Printf("  ✗ %v\n", err)
-				failureCount++
-			} else {
-				fmt.Printf("  ✓ Successfully cloned %s\n", repo.Name)
-				successCount++
+			if err := writePlan(os.Stdout, entries, outputFormat); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing plan: %v\n", err)
+				os.Exit(1)
 			}
+			if prunePolicy != "" {
+				pruneOrphans(ctx, targetDir, sources, candidates, runner)
+			}
+			return
 		}
 
-		fmt.Println(strings.Repeat("-", 50))
-		fmt.Printf("Completed! Success: %d, Failed: %d\n", successCount, failureCount)
+		summary, err := syncRepositories(ctx, os.Stdout, candidates, outputFormat)
+
+		for _, result := range summary.Results {
+			state.Record(result)
+		}
+		if saveErr := state.Save(); saveErr != nil {
+			fmt.Fprintf(info, "Warning: could not save state: %v\n", saveErr)
+		}
+		if prunePolicy != "" && ctx.Err() == nil {
+			pruneOrphans(ctx, targetDir, sources, candidates, runner)
+		}
+
+		fmt.Fprintln(info, strings.Repeat("-", 50))
+		if ctx.Err() != nil {
+			fmt.Fprintf(info, "Interrupted! Success: %d, Failed: %d, Not synced: %d\n", summary.Succeeded, summary.Failed, summary.Canceled)
+		} else {
+			fmt.Fprintf(info, "Completed! Success: %d, Failed: %d\n", summary.Succeeded, summary.Failed)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+			os.Exit(1)
+		}
+		if ctx.Err() != nil {
+			os.Exit(exitInterrupted)
+		}
+		if summary.Failed > 0 {
+			os.Exit(1)
+		}
 	},
 }
 
 func init() {
-	rootCmd.Flags().StringVarP(&orgName, "org", "o", "", "GitHub organization name")
-	rootCmd.MarkFlagRequired("org")
+	rootCmd.Flags().StringSliceVarP(&orgNames, "org", "o", nil, "GitHub organization name (repeatable)")
+	rootCmd.Flags().StringVarP(&userName, "user", "u", "", "GitHub user whose repositories to sync; use @me for the token owner")
+	rootCmd.Flags().StringVar(&affiliation, "affiliation", gitgrab.DefaultAffiliation, "Repositories of the token owner to include: any of owner, collaborator, organization_member")
+	rootCmd.Flags().StringSliceVar(&teamSlugs, "team", nil, "Only sync repositories of this team in the --org organization (repeatable)")
+	rootCmd.Flags().BoolVar(&teamChildren, "team-children", false, "Include repositories of child teams of --team")
+	rootCmd.Flags().StringVar(&teamPermission, "team-permission", "", "Minimum team permission on a repository: pull, triage, push, maintain or admin")
+	rootCmd.Flags().BoolVar(&allMyOrgs, "all-my-orgs", false, "Sync every organization the token owner belongs to")
+	rootCmd.Flags().StringVarP(&configPath, "config", "c", "", "Configuration file (default: "+gitgrab.ConfigFileName+" in the target directory or $XDG_CONFIG_HOME/gitgrab)")
+	rootCmd.Flags().StringVar(&layout, "layout", gitgrab.LayoutAuto, "Directory layout: 'flat', 'owner', 'auto' to nest by owner only when repositories of several owners are synced, or a template such as '{host}/{owner}/{name}'")
 	rootCmd.Flags().StringVarP(&cloneMethod, "method", "m", "ssh", "Clone method for repositories: 'ssh' or 'http' (default: ssh)")
+	rootCmd.Flags().StringVar(&backend, "backend", gitgrab.BackendGit, "Git implementation: 'git' to run the git binary, or 'go-git' to work without it")
+	rootCmd.Flags().StringVar(&remoteMismatch, "remote-mismatch", gitgrab.RemoteMismatchSkip, "What to do with an existing checkout whose origin is another repository: 'skip', 'rewrite' the remote, or 'fail'")
+	rootCmd.Flags().StringVar(&updateStrategy, "update-strategy", gitgrab.UpdateFFOnly, "How to pull the default branch: 'ff-only', 'rebase', 'autostash' or 'skip-if-dirty'")
+	rootCmd.Flags().BoolVar(&allBranches, "all-branches", false, "Also fast-forward local branches other than the checked out one, and report diverged branches")
+	rootCmd.Flags().BoolVar(&archivedDir, "archived-dir", false, "Place archived repositories below archived/ in the target directory")
+	rootCmd.Flags().StringVar(&prunePolicy, "prune", "", "What to do with clones of repositories no longer listed upstream: 'report', 'attic' (move to _attic/) or 'delete' (only without unpushed work)")
+	rootCmd.Flags().BoolVar(&fixRemotes, "fix-remotes", false, "Rewrite the origin remote of existing repositories to match --method")
+	rootCmd.Flags().IntVarP(&jobs, "jobs", "j", gitgrab.DefaultJobs, "Number of repositories to clone or update concurrently")
+	rootCmd.Flags().DurationVar(&apiTimeout, "api-timeout", time.Minute, "Longest time a single GitHub API request may take (0 for no limit)")
+	rootCmd.Flags().DurationVar(&repoTimeout, "timeout", 0, "Longest time to spend cloning or updating a single repository (0 for no limit)")
+	rootCmd.Flags().DurationVar(&maxRateLimitWait, "max-rate-limit-wait", gitgrab.DefaultMaxRateLimitWait, "Longest time to wait for GitHub API rate limits to reset before failing")
+	rootCmd.Flags().StringSliceVar(&includePatterns, "include", nil, "Only sync repositories whose name matches a glob, or a regex wrapped in slashes (repeatable)")
+	rootCmd.Flags().StringSliceVar(&excludePatterns, "exclude", nil, "Skip repositories whose name matches a glob, or a regex wrapped in slashes (repeatable)")
+	rootCmd.Flags().BoolVar(&noForks, "no-forks", false, "Skip forked repositories")
+	rootCmd.Flags().BoolVar(&noArchived, "no-archived", false, "Skip archived repositories")
+	rootCmd.Flags().StringSliceVar(&topics, "topic", nil, "Only sync repositories tagged with one of these topics (repeatable)")
+	rootCmd.Flags().StringSliceVar(&languages, "language", nil, "Only sync repositories whose primary language is one of these (repeatable)")
+	rootCmd.Flags().StringSliceVar(&visibilities, "visibility", nil, "Only sync repositories with one of these visibilities: public, private, internal (repeatable)")
+	rootCmd.Flags().StringVar(&hostName, "host", "", "GitHub host, e.g. a GitHub Enterprise Server hostname (env: GH_HOST, default: github.com)")
+	rootCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be cloned, pulled, fetched or skipped without changing anything")
+	rootCmd.Flags().StringVar(&outputFormat, "output", outputText, "Output format: 'text', or 'json', 'ndjson' or 'junit' for a machine-readable report on stdout")
+	rootCmd.Flags().StringVar(&apiURL, "api-url", "", "GitHub REST API base URL (env: GITHUB_API_URL, default: derived from --host)")
+}
+
+// exitInterrupted is the conventional exit status after SIGINT
+const exitInterrupted = 130
+
+// interruptContext returns a context that is canceled on the first SIGINT or
+// SIGTERM, letting the sync stop cleanly. Signals are no longer caught after
+// that, so a second Ctrl-C terminates immediately.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		fmt.Fprintln(os.Stderr, "\nInterrupted, stopping... (press Ctrl-C again to quit immediately)")
+		cancel()
+	}()
+	return ctx
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	if err := rootCmd.ExecuteContext(interruptContext()); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/scottbrown/gitgrab"
+)
+
+const (
+	outputText   = "text"
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+	outputJUnit  = "junit"
+)
+
+var (
+	dryRun       bool
+	outputFormat string
+)
+
+// writePlan renders a dry-run plan as a table, a JSON array or one JSON
+// object per line
+func writePlan(w io.Writer, entries []gitgrab.PlanEntry, format string) error {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case outputNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	counts := make(map[gitgrab.Action]int)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACTION\tREPOSITORY\tPATH\tDETAIL")
+	for _, entry := range entries {
+		counts[entry.Action]++
+		detail := entry.Reason
+		if entry.MovedFrom != "" {
+			detail += ", moving from " + entry.MovedFrom
+		}
+		if entry.Origin != "" {
+			detail += ", rewriting origin " + entry.Origin
+		}
+		if entry.RenamedFrom != "" {
+			detail += ", default branch renamed from " + entry.RenamedFrom
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", entry.Action, entry.Repository, entry.Path, detail)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\nPlan: %d to clone, %d to reclone, %d to pull, %d to fetch, %d skipped\n",
+		counts[gitgrab.ActionClone], counts[gitgrab.ActionReclone], counts[gitgrab.ActionPull], counts[gitgrab.ActionFetch], counts[gitgrab.ActionSkip])
+	return err
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/scottbrown/gitgrab"
+)
+
+var prunePolicy string
+
+// pruneOrphans applies the prune policy to clones in targetDir that match
+// none of the candidates. A dry run only reports them.
+func pruneOrphans(ctx context.Context, targetDir string, sources []gitgrab.Source, candidates []candidate, runner gitgrab.GitRunner) {
+	config := gitgrab.PruneConfig{
+		TargetDir: targetDir,
+		Policy:    prunePolicy,
+		Reporter:  gitgrab.NewConsoleReporter(info),
+		Git:       runner,
+	}
+	if dryRun {
+		config.Policy = gitgrab.PruneReport
+	}
+
+	// Teams and the token owner's affiliations list only some of an
+	// account's repositories, so only organizations and users listed in
+	// full can have orphans
+	for _, src := range sources {
+		if src.Kind == gitgrab.SourceOrganization || (src.Kind == gitgrab.SourceUser && !src.IsAuthenticatedUser()) {
+			config.Owners = append(config.Owners, src.Name)
+		}
+	}
+	// A repository still to be moved after a rename is not orphaned
+	for _, c := range candidates {
+		config.Listed = append(config.Listed, c.config.RepoPath())
+		if c.config.Previous != nil {
+			config.Listed = append(config.Listed, c.config.Previous.Path)
+		}
+	}
+
+	orphans, err := gitgrab.FindOrphans(ctx, config)
+	if err != nil {
+		fmt.Fprintf(info, "Warning: could not look for orphaned clones: %v\n", err)
+		return
+	}
+	if len(orphans) == 0 {
+		return
+	}
+	// Clones that could not be pruned are reported as warnings and left
+	// for the next run
+	fmt.Fprintln(info)
+	gitgrab.Prune(ctx, config, orphans)
+}
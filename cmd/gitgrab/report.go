@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/scottbrown/gitgrab"
+)
+
+// syncRepositories syncs every candidate that isn't skipped and writes a
+// report in the requested format; text output needs no report. The NDJSON
+// event stream is written as repositories finish, the other formats once
+// the sync is complete.
+func syncRepositories(ctx context.Context, w io.Writer, candidates []candidate, format string) (gitgrab.SyncSummary, error) {
+	start := time.Now()
+
+	reporter := gitgrab.NewConsoleReporter(info)
+
+	var configs []gitgrab.CloneConfig
+	for _, c := range candidates {
+		if c.skip == "" {
+			configs = append(configs, c.config)
+			continue
+		}
+		reporter.Report(gitgrab.Event{
+			Kind:       gitgrab.EventSkipped,
+			Repository: c.config.Repository,
+			Path:       c.config.RepoPath(),
+			Action:     gitgrab.ActionSkip,
+			Message:    c.skip,
+		})
+	}
+
+	syncer := gitgrab.NewSyncer(jobs, reporter)
+	syncer.Timeout = repoTimeout
+
+	var events *gitgrab.EventWriter
+	var writeErr error
+	if format == outputNDJSON {
+		events = gitgrab.NewEventWriter(w)
+		for _, c := range candidates {
+			if c.skip != "" && writeErr == nil {
+				writeErr = events.WriteRecord(gitgrab.SkippedRecord(c.config, c.skip))
+			}
+		}
+		syncer.OnResult = func(result gitgrab.SyncResult) {
+			if writeErr == nil {
+				writeErr = events.WriteRecord(gitgrab.NewReportRecord(result))
+			}
+		}
+	}
+
+	summary := syncer.SyncContext(ctx, configs)
+
+	switch format {
+	case outputNDJSON:
+		if writeErr != nil {
+			return summary, writeErr
+		}
+		return summary, events.WriteSummary(time.Since(start))
+	case outputJSON, outputJUnit:
+		report := gitgrab.NewReport(reportRecords(candidates, summary.Results), time.Since(start))
+		if format == outputJUnit {
+			return summary, report.WriteJUnit(w)
+		}
+		return summary, report.WriteJSON(w)
+	}
+	return summary, nil
+}
+
+// reportRecords lists a record for every candidate, in order; results holds
+// the outcome of each candidate that wasn't skipped
+func reportRecords(candidates []candidate, results []gitgrab.SyncResult) []gitgrab.ReportRecord {
+	records := make([]gitgrab.ReportRecord, 0, len(candidates))
+	next := 0
+	for _, c := range candidates {
+		if c.skip != "" {
+			records = append(records, gitgrab.SkippedRecord(c.config, c.skip))
+			continue
+		}
+		records = append(records, gitgrab.NewReportRecord(results[next]))
+		next++
+	}
+	return records
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/scottbrown/gitgrab"
+	"github.com/spf13/cobra"
+)
+
+var scrubCmd = &cobra.Command{
+	Use:   "scrub-remotes [target_directory]",
+	Short: "Remove embedded tokens from the remotes of existing clones",
+	Long:  "Earlier versions of gitgrab stored the GitHub token in the origin URL of private repositories cloned over HTTP. This command rewrites those remotes to their token-free form.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runner, err := gitgrab.NewGitRunner(backend)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		repos, err := gitgrab.FindRepositories(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error scanning %s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+
+		scrubbed := 0
+		failed := 0
+		for _, repoPath := range repos {
+			changed, err := gitgrab.ScrubRemoteCredentials(runner, repoPath)
+			switch {
+			case err != nil:
+				fmt.Printf("  ✗ %s: %v\n", repoPath, err)
+				failed++
+			case changed:
+				fmt.Printf("  ✓ Removed credentials from %s\n", repoPath)
+				scrubbed++
+			}
+		}
+
+		fmt.Printf("Checked %d repositories, scrubbed %d, failed %d\n", len(repos), scrubbed, failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	scrubCmd.Flags().StringVar(&backend, "backend", gitgrab.BackendGit, "Git implementation: 'git' to run the git binary, or 'go-git' to work without it")
+	rootCmd.AddCommand(scrubCmd)
+}
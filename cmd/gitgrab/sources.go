@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/scottbrown/gitgrab"
+	"github.com/spf13/cobra"
+)
+
+var (
+	orgNames    []string
+	userName    string
+	allMyOrgs   bool
+	affiliation string
+
+	teamSlugs      []string
+	teamChildren   bool
+	teamPermission string
+)
+
+// sourceFlags are the flags that select what to sync; when none of them is
+// given, the sources from the configuration file are used
+var sourceFlags = []string{"org", "team", "user", "all-my-orgs"}
+
+// buildSources determines the accounts to sync from the --org, --team,
+// --user and --all-my-orgs flags, or from the configuration file
+func buildSources(cmd *cobra.Command, client *gitgrab.GitHubClient, cfg *gitgrab.Config) ([]gitgrab.Source, error) {
+	var sources []gitgrab.Source
+	expand := allMyOrgs
+	if cfg != nil && !anyChanged(cmd, sourceFlags...) {
+		sources = cfg.Sources.SourceList()
+		expand = cfg.Sources.AllMyOrgs
+	} else {
+		var err error
+		if sources, err = flagSources(); err != nil {
+			return nil, err
+		}
+	}
+
+	var me *gitgrab.User
+	for i, src := range sources {
+		if src.Kind != gitgrab.SourceUser || src.IsAuthenticatedUser() {
+			continue
+		}
+		// The token owner's own repositories are listed through
+		// /user/repos so private ones are included
+		if me == nil {
+			user, err := client.FetchAuthenticatedUserContext(cmd.Context())
+			if err != nil {
+				return nil, fmt.Errorf("checking whether %s is the token owner: %w", src.Name, err)
+			}
+			me = &user
+		}
+		if strings.EqualFold(me.Login, src.Name) {
+			sources[i].Name = gitgrab.AuthenticatedUser
+		}
+	}
+
+	if expand {
+		orgs, err := client.ExpandOrganizationsContext(cmd.Context(), sources)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, orgs...)
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("no repositories to sync: specify --org, --user or --all-my-orgs, or add sources to " + gitgrab.ConfigFileName)
+	}
+	return sources, nil
+}
+
+// flagSources turns the --org, --team and --user flags into sources
+func flagSources() ([]gitgrab.Source, error) {
+	var sources []gitgrab.Source
+	if len(teamSlugs) > 0 {
+		teams, err := buildTeamSources()
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, teams...)
+	} else {
+		for _, name := range orgNames {
+			sources = append(sources, gitgrab.OrganizationSource(gitgrab.OrganizationName(name)))
+		}
+	}
+
+	if userName != "" {
+		user := gitgrab.UserSource(userName)
+		user.Affiliation = affiliation
+		sources = append(sources, user)
+	}
+	return sources, nil
+}
+
+// buildTeamSources narrows the single --org to the teams given with --team
+func buildTeamSources() ([]gitgrab.Source, error) {
+	if len(orgNames) != 1 {
+		return nil, errors.New("--team requires exactly one --org")
+	}
+
+	opts := gitgrab.TeamRepoOptions{IncludeChildTeams: teamChildren}
+	if teamPermission != "" {
+		permission, err := gitgrab.ParsePermission(teamPermission)
+		if err != nil {
+			return nil, err
+		}
+		opts.MinPermission = permission
+	}
+
+	var sources []gitgrab.Source
+	for _, slug := range teamSlugs {
+		sources = append(sources, gitgrab.TeamSource(gitgrab.OrganizationName(orgNames[0]), slug, opts))
+	}
+	return sources, nil
+}
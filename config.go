@@ -0,0 +1,327 @@
+package gitgrab
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName is the name of the configuration file gitgrab looks for
+const ConfigFileName = "gitgrab.yaml"
+
+// Layout names accepted for Config.Layout, besides templates
+const (
+	// LayoutAuto nests repositories under their owner only when several
+	// owners are synced
+	LayoutAuto = "auto"
+	LayoutFlat = "flat"
+	// LayoutOwner always nests repositories under their owner
+	LayoutOwner = "owner"
+)
+
+// Config is the declarative form of a sync, loaded from gitgrab.yaml
+type Config struct {
+	Sources SourcesConfig `yaml:"sources"`
+	Filters FiltersConfig `yaml:"filters"`
+	Method  string        `yaml:"method"`
+	Layout  string        `yaml:"layout"`
+	Jobs    int           `yaml:"jobs"`
+	Host    string        `yaml:"host"`
+	APIURL  string        `yaml:"api_url"`
+	Backend string        `yaml:"backend"`
+	// RemoteMismatch is the policy for checkouts of another repository
+	RemoteMismatch string `yaml:"remote_mismatch"`
+	// FixRemotes switches existing repositories to the configured method
+	FixRemotes     bool   `yaml:"fix_remotes"`
+	UpdateStrategy string `yaml:"update_strategy"`
+	AllBranches    bool   `yaml:"all_branches"`
+	// ArchivedDir places archived repositories below ArchivedDir
+	ArchivedDir bool `yaml:"archived_dir"`
+	// Prune is the policy for clones of repositories no longer listed
+	Prune string `yaml:"prune"`
+	// Repos holds per-repository overrides keyed by name or owner/name
+	Repos map[string]RepoOverride `yaml:"repos"`
+
+	path string
+}
+
+type SourcesConfig struct {
+	Orgs        []string     `yaml:"orgs"`
+	Users       []string     `yaml:"users"`
+	Teams       []TeamConfig `yaml:"teams"`
+	AllMyOrgs   bool         `yaml:"all_my_orgs"`
+	Affiliation string       `yaml:"affiliation"`
+}
+
+type TeamConfig struct {
+	Org        string `yaml:"org"`
+	Slug       string `yaml:"slug"`
+	Children   bool   `yaml:"children"`
+	Permission string `yaml:"permission"`
+}
+
+type FiltersConfig struct {
+	Include    []string `yaml:"include"`
+	Exclude    []string `yaml:"exclude"`
+	NoForks    bool     `yaml:"no_forks"`
+	NoArchived bool     `yaml:"no_archived"`
+	Topics     []string `yaml:"topics"`
+	Languages  []string `yaml:"languages"`
+	Visibility []string `yaml:"visibility"`
+}
+
+// RepoOverride changes how a single repository is synced
+type RepoOverride struct {
+	// Branch is checked out on clone and pulled on update instead of the
+	// repository's default branch
+	Branch string `yaml:"branch"`
+	Method string `yaml:"method"`
+	Skip   bool   `yaml:"skip"`
+}
+
+// ConfigError describes an invalid value in a configuration file
+type ConfigError struct {
+	File string
+	Key  string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.File, e.Key, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// FindConfig looks for gitgrab.yaml in the target directory, then in
+// $XDG_CONFIG_HOME/gitgrab (defaulting to ~/.config/gitgrab). It returns an
+// empty path when no file exists.
+func FindConfig(targetDir string) string {
+	candidates := []string{filepath.Join(targetDir, ConfigFileName)}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		candidates = append(candidates, filepath.Join(configHome, "gitgrab", ConfigFileName))
+	}
+
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// LoadConfig reads and validates a configuration file. Unknown keys are
+// rejected so typos don't silently fall back to defaults.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{path: path}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, &ConfigError{File: path, Err: err}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Path returns the file the configuration was loaded from
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Validate checks every value and reports all invalid keys at once
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key string, err error) {
+		errs = append(errs, &ConfigError{File: c.path, Key: key, Err: err})
+	}
+
+	if c.Method != "" {
+		if err := validateCloneMethod(c.Method); err != nil {
+			invalid("method", err)
+		}
+	}
+	if err := ValidateLayout(c.Layout); err != nil {
+		invalid("layout", err)
+	}
+	if c.Jobs < 0 {
+		invalid("jobs", fmt.Errorf("must not be negative"))
+	}
+	if _, err := NewGitRunner(c.Backend); err != nil {
+		invalid("backend", err)
+	}
+	if err := ValidateRemoteMismatch(c.RemoteMismatch); err != nil {
+		invalid("remote_mismatch", err)
+	}
+	if err := ValidateUpdateStrategy(c.UpdateStrategy, c.Backend); err != nil {
+		invalid("update_strategy", err)
+	}
+	if err := ValidatePrune(c.Prune); err != nil {
+		invalid("prune", err)
+	}
+
+	for i, team := range c.Sources.Teams {
+		key := fmt.Sprintf("sources.teams[%d]", i)
+		if team.Org == "" {
+			invalid(key+".org", errors.New("is required"))
+		}
+		if team.Slug == "" {
+			invalid(key+".slug", errors.New("is required"))
+		}
+		if team.Permission != "" {
+			if _, err := ParsePermission(team.Permission); err != nil {
+				invalid(key+".permission", err)
+			}
+		}
+	}
+
+	for i, value := range c.Filters.Include {
+		if _, err := ParsePattern(value); err != nil {
+			invalid(fmt.Sprintf("filters.include[%d]", i), err)
+		}
+	}
+	for i, value := range c.Filters.Exclude {
+		if _, err := ParsePattern(value); err != nil {
+			invalid(fmt.Sprintf("filters.exclude[%d]", i), err)
+		}
+	}
+	for i, value := range c.Filters.Visibility {
+		if _, err := ParseVisibility(value); err != nil {
+			invalid(fmt.Sprintf("filters.visibility[%d]", i), err)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(c.Repos)) {
+		if name == "" {
+			invalid("repos", errors.New("repository name must not be empty"))
+		}
+		if method := c.Repos[name].Method; method != "" {
+			if err := validateCloneMethod(method); err != nil {
+				invalid(fmt.Sprintf("repos.%s.method", name), err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateCloneMethod(s string) error {
+	switch strings.ToLower(s) {
+	case "ssh", "http":
+		return nil
+	default:
+		return fmt.Errorf("invalid clone method: %s", s)
+	}
+}
+
+// SourceList returns the sources described by the configuration. The
+// all_my_orgs setting needs an API call and is expanded by the caller.
+func (c SourcesConfig) SourceList() []Source {
+	var sources []Source
+	for _, org := range c.Orgs {
+		sources = append(sources, OrganizationSource(OrganizationName(org)))
+	}
+	for _, team := range c.Teams {
+		permission, _ := ParsePermission(team.Permission)
+		sources = append(sources, TeamSource(OrganizationName(team.Org), team.Slug, TeamRepoOptions{
+			IncludeChildTeams: team.Children,
+			MinPermission:     permission,
+		}))
+	}
+	for _, user := range c.Users {
+		src := UserSource(user)
+		src.Affiliation = c.Affiliation
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+// Filter converts the filter settings into a Filter
+func (c FiltersConfig) Filter() (Filter, error) {
+	include, err := ParsePatterns(c.Include)
+	if err != nil {
+		return Filter{}, err
+	}
+	exclude, err := ParsePatterns(c.Exclude)
+	if err != nil {
+		return Filter{}, err
+	}
+
+	filter := Filter{
+		Include:    include,
+		Exclude:    exclude,
+		NoForks:    c.NoForks,
+		NoArchived: c.NoArchived,
+		Topics:     c.Topics,
+		Languages:  c.Languages,
+	}
+	for _, value := range c.Visibility {
+		v, err := ParseVisibility(value)
+		if err != nil {
+			return Filter{}, err
+		}
+		filter.Visibility = append(filter.Visibility, v)
+	}
+	return filter, nil
+}
+
+// Override returns the per-repository settings for repo, matching either
+// owner/name or the bare repository name
+func (c *Config) Override(repo Repository) (RepoOverride, bool) {
+	for name, override := range c.Repos {
+		if strings.EqualFold(name, repo.Owner.Login+"/"+repo.Name.String()) {
+			return override, true
+		}
+	}
+	for name, override := range c.Repos {
+		if strings.EqualFold(name, repo.Name.String()) {
+			return override, true
+		}
+	}
+	return RepoOverride{}, false
+}
+
+// Apply adjusts a clone configuration with the repository's overrides. It
+// returns false when the repository should be skipped entirely.
+func (c *Config) Apply(config CloneConfig) (CloneConfig, bool) {
+	override, ok := c.Override(config.Repository)
+	if !ok {
+		return config, true
+	}
+	if override.Skip {
+		return config, false
+	}
+	if override.Branch != "" {
+		config.Branch = BranchName(override.Branch)
+	}
+	if override.Method != "" {
+		config.Method, _ = ParseCloneMethod(override.Method)
+	}
+	return config, true
+}
@@ -0,0 +1,174 @@
+package gitgrab
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, ConfigFileName)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), `
+sources:
+  orgs: [acme]
+  users: ["@me"]
+  affiliation: owner,collaborator
+  teams:
+    - org: acme
+      slug: platform
+      children: true
+      permission: push
+filters:
+  include: ["api-*"]
+  no_archived: true
+  visibility: [private]
+method: http
+layout: owner
+jobs: 8
+repos:
+  api-gateway:
+    branch: develop
+  acme/api-legacy:
+    skip: true
+  api-tools:
+    method: ssh
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if config.Method != "http" || config.Layout != LayoutOwner || config.Jobs != 8 {
+		t.Errorf("Unexpected top-level settings: %+v", config)
+	}
+
+	sources := config.Sources.SourceList()
+	if len(sources) != 3 {
+		t.Fatalf("Expected 3 sources, got %+v", sources)
+	}
+	if sources[1].Kind != SourceTeam || sources[1].Team != "platform" || !sources[1].TeamOptions.IncludeChildTeams || sources[1].TeamOptions.MinPermission != PermissionPush {
+		t.Errorf("Unexpected team source: %+v", sources[1])
+	}
+	if !sources[2].IsAuthenticatedUser() || sources[2].Affiliation != "owner,collaborator" {
+		t.Errorf("Unexpected user source: %+v", sources[2])
+	}
+
+	filter, err := config.Filters.Filter()
+	if err != nil {
+		t.Fatalf("Expected no error building filter, got %v", err)
+	}
+	if !filter.NoArchived || len(filter.Include) != 1 || len(filter.Visibility) != 1 {
+		t.Errorf("Unexpected filter: %+v", filter)
+	}
+}
+
+func TestConfig_Apply(t *testing.T) {
+	config := &Config{Repos: map[string]RepoOverride{
+		"api-gateway":     {Branch: "develop", Method: "http"},
+		"acme/api-legacy": {Skip: true},
+	}}
+	owner := RepositoryOwner{Login: "acme"}
+
+	clone, keep := config.Apply(CloneConfig{Repository: Repository{Name: "api-gateway", Owner: owner}, Method: CloneMethodSSH})
+	if !keep || clone.Branch != "develop" || clone.Method != CloneMethodHTTP {
+		t.Errorf("Expected branch and method override, got %+v (keep %v)", clone, keep)
+	}
+
+	if _, keep := config.Apply(CloneConfig{Repository: Repository{Name: "api-legacy", Owner: owner}}); keep {
+		t.Error("Expected api-legacy to be skipped")
+	}
+
+	// owner/name keys must not match repositories of other owners
+	other := RepositoryOwner{Login: "widgets"}
+	if _, keep := config.Apply(CloneConfig{Repository: Repository{Name: "api-legacy", Owner: other}}); !keep {
+		t.Error("Expected api-legacy of another owner to be kept")
+	}
+}
+
+func TestLoadConfig_ValidationErrorsNameKeys(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), `
+method: ftp
+layout: nested
+backend: libgit2
+remote_mismatch: ignore
+update_strategy: merge
+prune: archive
+sources:
+  teams:
+    - org: acme
+filters:
+  include: ["/([a-z/"]
+repos:
+  api-gateway:
+    method: svn
+`)
+
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatal("Expected validation errors, got none")
+	}
+
+	for _, key := range []string{"method", "layout", "backend", "remote_mismatch", "update_strategy", "prune", "sources.teams[0].slug", "filters.include[0]", "repos.api-gateway.method"} {
+		if !strings.Contains(err.Error(), path+": "+key+": ") {
+			t.Errorf("Expected error for key %s, got %v", key, err)
+		}
+	}
+
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Errorf("Expected ConfigError, got %T", err)
+	}
+}
+
+func TestLoadConfig_RebaseNeedsGitBackend(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), "backend: go-git\nupdate_strategy: rebase\n")
+
+	_, err := LoadConfig(path)
+	if err == nil || !strings.Contains(err.Error(), path+": update_strategy: ") {
+		t.Errorf("Expected an update_strategy error, got %v", err)
+	}
+}
+
+func TestLoadConfig_UnknownKey(t *testing.T) {
+	path := writeConfig(t, t.TempDir(), "filters:\n  no_fork: true\n")
+
+	_, err := LoadConfig(path)
+	if err == nil {
+		t.Fatal("Expected error for unknown key, got none")
+	}
+	if !strings.Contains(err.Error(), "no_fork") {
+		t.Errorf("Expected error to name the unknown key, got %v", err)
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	targetDir := t.TempDir()
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	if path := FindConfig(targetDir); path != "" {
+		t.Errorf("Expected no config, got %s", path)
+	}
+
+	os.MkdirAll(filepath.Join(configHome, "gitgrab"), 0755)
+	userConfig := writeConfig(t, filepath.Join(configHome, "gitgrab"), "jobs: 2\n")
+	if path := FindConfig(targetDir); path != userConfig {
+		t.Errorf("Expected %s, got %s", userConfig, path)
+	}
+
+	targetConfig := writeConfig(t, targetDir, "jobs: 4\n")
+	if path := FindConfig(targetDir); path != targetConfig {
+		t.Errorf("Expected target directory config %s to take precedence, got %s", targetConfig, path)
+	}
+}
@@ -0,0 +1,128 @@
+package gitgrab
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tokenEnvVar carries the token to the credential helper. Environment
+// variables are not visible in process listings the way arguments are.
+const tokenEnvVar = "GITGRAB_TOKEN"
+
+// credentialHelper is an inline git credential helper that answers "get"
+// requests with the token from tokenEnvVar. GitHub accepts any username
+// alongside a token.
+const credentialHelper = `!f() { test "$1" = get || exit 0; echo username=x-access-token; echo "password=${` + tokenEnvVar + `}"; }; f`
+
+// runGit runs git through the configured runner, authenticating HTTPS
+// requests to the configured host with the token through an ephemeral
+// credential helper, so the token never appears in arguments or in the
+// stored remote URL.
+func runGit(ctx context.Context, config CloneConfig, dir string, args ...string) (string, error) {
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+
+	var full []string
+	if !config.Token.IsEmpty() {
+		full = append(full,
+			// An empty value resets helpers inherited from user config
+			"-c", "credential.helper=",
+			"-c", fmt.Sprintf("credential.https://%s.helper=%s", config.Host, credentialHelper),
+		)
+		env = append(env, tokenEnvVar+"="+config.Token.String())
+	}
+
+	return config.git().Run(ctx, dir, env, append(full, args...)...)
+}
+
+// tokenPrefixes start the tokens GitHub issues: personal access tokens,
+// fine-grained ones, OAuth, user-to-server, server-to-server and refresh
+// tokens, and old installation tokens
+var tokenPrefixes = []string{"ghp_", "github_pat_", "gho_", "ghu_", "ghs_", "ghr_", "v1."}
+
+// looksLikeToken reports whether a URL username is a GitHub token, as
+// written by older versions of gitgrab, rather than an account name
+func looksLikeToken(username string) bool {
+	for _, prefix := range tokenPrefixes {
+		if strings.HasPrefix(username, prefix) {
+			return true
+		}
+	}
+	// Classic tokens are 40 hex digits without a prefix
+	if len(username) != 40 {
+		return false
+	}
+	for _, r := range username {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// stripCredentials removes a password, or a username that is a token, from
+// an HTTP(S) remote URL. A plain username is kept, since it may be set on
+// purpose to select a credential.
+func stripCredentials(remote string) (string, bool) {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return remote, false
+	}
+	if _, hasPassword := u.User.Password(); !hasPassword && !looksLikeToken(u.User.Username()) {
+		return remote, false
+	}
+	u.User = nil
+	return u.String(), true
+}
+
+// ScrubRemoteCredentials rewrites the origin remote of the repository at
+// repoPath to drop credentials embedded in its URL, as written by older
+// versions of gitgrab, running git with the given runner; nil means
+// ExecGitRunner. It reports whether the remote was changed.
+func ScrubRemoteCredentials(git GitRunner, repoPath string) (bool, error) {
+	if git == nil {
+		git = ExecGitRunner{}
+	}
+	return scrubRemoteCredentials(context.Background(), git, repoPath)
+}
+
+func scrubRemoteCredentials(ctx context.Context, git GitRunner, repoPath string) (bool, error) {
+	output, err := git.Run(ctx, repoPath, nil, "remote", "get-url", "origin")
+	if err != nil {
+		return false, fmt.Errorf("failed to read origin remote: %v", err)
+	}
+
+	clean, changed := stripCredentials(strings.TrimSpace(output))
+	if !changed {
+		return false, nil
+	}
+
+	if _, err := git.Run(ctx, repoPath, nil, "remote", "set-url", "origin", clean); err != nil {
+		return false, fmt.Errorf("failed to update origin remote: %v", err)
+	}
+	return true, nil
+}
+
+// FindRepositories returns the working tree roots of all git repositories
+// below root. Repositories nested inside other repositories are not reported.
+func FindRepositories(root string) ([]string, error) {
+	var repos []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if _, err := os.Lstat(filepath.Join(path, ".git")); err == nil {
+			repos = append(repos, path)
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return repos, err
+}
@@ -0,0 +1,175 @@
+package gitgrab
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// EventKind identifies a step in syncing a repository
+type EventKind string
+
+const (
+	// EventStarted is reported by a Syncer before a repository's other events
+	EventStarted EventKind = "started"
+	// EventUpdating is reported when the repository already exists locally
+	EventUpdating EventKind = "updating"
+	// EventMoved is reported when a repository renamed or transferred since
+	// the last sync was moved to its new directory; Message holds the old
+	// directory
+	EventMoved EventKind = "moved"
+	// EventRecloning is reported when the directory holds a broken
+	// repository that is replaced by a fresh clone; Message gives the reason
+	EventRecloning EventKind = "recloning"
+	// EventRemoteScrubbed is reported when credentials were removed from
+	// the origin remote
+	EventRemoteScrubbed EventKind = "remote_scrubbed"
+	// EventRemoteRewritten is reported when an origin remote pointing at
+	// another repository, or using another clone method, was replaced;
+	// Message holds the old URL
+	EventRemoteRewritten EventKind = "remote_rewritten"
+	EventWarning         EventKind = "warning"
+	EventPulling         EventKind = "pulling"
+	EventFetching        EventKind = "fetching"
+	EventCloned          EventKind = "cloned"
+	EventPulled          EventKind = "pulled"
+	EventFetched         EventKind = "fetched"
+	EventSkipped         EventKind = "skipped"
+	// EventBranchRenamed is reported when the clone was migrated to a
+	// default branch renamed upstream; Branch is the new name and Message
+	// the old one
+	EventBranchRenamed EventKind = "branch_renamed"
+	// EventDefaultBranchChanged is reported when upstream made another
+	// existing branch the default, keeping the old one; Branch is the new
+	// default and Message the old one
+	EventDefaultBranchChanged EventKind = "default_branch_changed"
+	// EventBranchUpdated is reported for each branch other than the checked
+	// out one that was fast-forwarded to its upstream
+	EventBranchUpdated EventKind = "branch_updated"
+	// EventBranchDiverged is reported for each branch that has commits its
+	// upstream doesn't have and vice versa; Message names the upstream
+	EventBranchDiverged EventKind = "branch_diverged"
+	// EventArchived is reported when the working tree of a repository
+	// archived upstream was made read-only after its final sync
+	EventArchived EventKind = "archived"
+	// EventOrphaned is reported for a clone of a repository no longer
+	// listed upstream that is left in place; Message holds its origin
+	EventOrphaned EventKind = "orphaned"
+	// EventPruned is reported when an orphaned clone was moved to the attic
+	// or deleted; Message holds its new path, or is empty when deleted
+	EventPruned EventKind = "pruned"
+	// EventSynced and EventFailed are reported by a Syncer once a
+	// repository is done
+	EventSynced EventKind = "synced"
+	EventFailed EventKind = "failed"
+)
+
+// Event describes something that happened while syncing a repository
+type Event struct {
+	Kind       EventKind
+	Repository Repository
+	Path       string
+	Action     Action
+	// Branch is the branch being pulled, or the branch checked out when
+	// fetching
+	Branch string
+	// Message carries the text of warnings and the reason for skips
+	Message string
+	Err     error
+	// Index and Total give the position of the repository in a sync run,
+	// counting from 1; both are zero outside a Syncer
+	Index int
+	Total int
+}
+
+// Reporter receives events as repositories are synced
+type Reporter interface {
+	Report(Event)
+}
+
+// ReporterFunc adapts a function to the Reporter interface
+type ReporterFunc func(Event)
+
+func (f ReporterFunc) Report(e Event) {
+	f(e)
+}
+
+// ConsoleReporter prints events as human-readable progress messages
+type ConsoleReporter struct {
+	W io.Writer
+}
+
+func NewConsoleReporter(w io.Writer) *ConsoleReporter {
+	return &ConsoleReporter{W: w}
+}
+
+func (r *ConsoleReporter) Report(e Event) {
+	w := r.W
+	if w == nil {
+		w = os.Stdout
+	}
+
+	name := e.Repository.Name
+	switch e.Kind {
+	case EventStarted:
+		fmt.Fprintf(w, "[%d/%d] Cloning %s...\n", e.Index, e.Total, name)
+	case EventMoved:
+		fmt.Fprintf(w, "  Moved %s from %s\n", name, e.Message)
+	case EventUpdating:
+		fmt.Fprintf(w, "  Directory %s already exists, updating...\n", name)
+	case EventRecloning:
+		fmt.Fprintf(w, "  Directory %s is not a usable repository (%s), recloning...\n", name, e.Message)
+	case EventRemoteScrubbed:
+		fmt.Fprintf(w, "  Removed embedded credentials from origin remote\n")
+	case EventRemoteRewritten:
+		fmt.Fprintf(w, "  Rewrote origin remote, which pointed at %s\n", e.Message)
+	case EventWarning:
+		fmt.Fprintf(w, "  Warning: %s\n", e.Message)
+	case EventPulling:
+		fmt.Fprintf(w, "  On default branch (%s), performing git pull...\n", e.Branch)
+	case EventFetching:
+		if e.Message != "" {
+			fmt.Fprintf(w, "  Warning: %s\n", e.Message)
+			fmt.Fprintf(w, "  Performing git fetch instead...\n")
+		} else {
+			fmt.Fprintf(w, "  On branch %s (not default), performing git fetch...\n", e.Branch)
+		}
+	case EventPulled:
+		fmt.Fprintf(w, "  ✓ Pulled latest changes for %s\n", name)
+	case EventFetched:
+		fmt.Fprintf(w, "  ✓ Fetched latest changes for %s\n", name)
+	case EventBranchRenamed:
+		fmt.Fprintf(w, "  Default branch renamed from %s to %s, migrated local clone\n", e.Message, e.Branch)
+	case EventDefaultBranchChanged:
+		fmt.Fprintf(w, "  Default branch changed from %s to %s, local branches left as they are\n", e.Message, e.Branch)
+	case EventBranchUpdated:
+		fmt.Fprintf(w, "  Fast-forwarded branch %s\n", e.Branch)
+	case EventBranchDiverged:
+		fmt.Fprintf(w, "  Warning: branch %s has diverged from %s and needs manual attention\n", e.Branch, e.Message)
+	case EventArchived:
+		fmt.Fprintf(w, "  Archived upstream: working tree made read-only, later syncs skip %s\n", name)
+	case EventOrphaned:
+		fmt.Fprintf(w, "Orphaned clone %s: %s is no longer listed upstream\n", e.Path, e.Message)
+	case EventPruned:
+		if e.Message != "" {
+			fmt.Fprintf(w, "Moved orphaned clone %s to %s\n", e.Path, e.Message)
+		} else {
+			fmt.Fprintf(w, "Deleted orphaned clone %s\n", e.Path)
+		}
+	case EventSkipped:
+		fmt.Fprintf(w, "Skipping %s: %s\n", name, e.Message)
+	case EventSynced:
+		fmt.Fprintf(w, "  ✓ Successfully cloned %s\n", name)
+	case EventFailed:
+		fmt.Fprintf(w, "  ✗ %v\n", e.Err)
+	}
+	// EventCloned needs no message of its own; a Syncer follows it with
+	// EventSynced
+}
+
+// eventLog records events so they can be replayed later
+type eventLog []Event
+
+func (l *eventLog) Report(e Event) {
+	*l = append(*l, e)
+}
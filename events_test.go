@@ -0,0 +1,83 @@
+package gitgrab
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConsoleReporter(t *testing.T) {
+	repo := Repository{Name: "api"}
+	tests := []struct {
+		event    Event
+		expected string
+	}{
+		{Event{Kind: EventStarted, Repository: repo, Index: 2, Total: 5}, "[2/5] Cloning api...\n"},
+		{Event{Kind: EventUpdating, Repository: repo}, "  Directory api already exists, updating...\n"},
+		{Event{Kind: EventPulling, Repository: repo, Branch: "main"}, "  On default branch (main), performing git pull...\n"},
+		{Event{Kind: EventFetching, Repository: repo, Branch: "feature"}, "  On branch feature (not default), performing git fetch...\n"},
+		{Event{Kind: EventFetching, Repository: repo, Message: "No default branch information for api"},
+			"  Warning: No default branch information for api\n  Performing git fetch instead...\n"},
+		{Event{Kind: EventPulled, Repository: repo}, "  ✓ Pulled latest changes for api\n"},
+		{Event{Kind: EventBranchRenamed, Repository: repo, Branch: "main", Message: "master"},
+			"  Default branch renamed from master to main, migrated local clone\n"},
+		{Event{Kind: EventDefaultBranchChanged, Repository: repo, Branch: "main", Message: "master"},
+			"  Default branch changed from master to main, local branches left as they are\n"},
+		{Event{Kind: EventBranchUpdated, Repository: repo, Branch: "release"}, "  Fast-forwarded branch release\n"},
+		{Event{Kind: EventBranchDiverged, Repository: repo, Branch: "wip", Message: "origin/wip"},
+			"  Warning: branch wip has diverged from origin/wip and needs manual attention\n"},
+		{Event{Kind: EventCloned, Repository: repo}, ""},
+		{Event{Kind: EventSkipped, Repository: repo, Message: "excluded by filters"}, "Skipping api: excluded by filters\n"},
+		{Event{Kind: EventSynced, Repository: repo}, "  ✓ Successfully cloned api\n"},
+		{Event{Kind: EventFailed, Repository: repo, Err: errors.New("failed to pull api: exit status 1")}, "  ✗ failed to pull api: exit status 1\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.event.Kind), func(t *testing.T) {
+			var buf bytes.Buffer
+			NewConsoleReporter(&buf).Report(tt.event)
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestSyncer_Sync_ReportsEvents(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "upstream")
+	initRepoOnBranch(t, source, "main")
+	targetDir := t.TempDir()
+	if err := exec.Command("git", "clone", "-q", source, filepath.Join(targetDir, "existing")).Run(); err != nil {
+		t.Fatalf("Failed to clone test repository: %v", err)
+	}
+
+	var kinds []EventKind
+	var positions []int
+	syncer := NewSyncer(2, ReporterFunc(func(e Event) {
+		kinds = append(kinds, e.Kind)
+		positions = append(positions, e.Index)
+	}))
+
+	summary := syncer.Sync([]CloneConfig{
+		{Repository: Repository{Name: "fresh", CloneURL: HTTPURL(source), DefaultBranch: "main"}, TargetDir: targetDir, Method: CloneMethodHTTP},
+		{Repository: Repository{Name: "existing", CloneURL: HTTPURL(source), DefaultBranch: "main"}, TargetDir: targetDir, Method: CloneMethodHTTP},
+	})
+	if summary.Failed != 0 {
+		t.Fatalf("Unexpected failures: %+v", summary.Results)
+	}
+
+	expectedKinds := []EventKind{
+		EventStarted, EventCloned, EventSynced,
+		EventStarted, EventUpdating, EventPulling, EventPulled, EventSynced,
+	}
+	if !reflect.DeepEqual(kinds, expectedKinds) {
+		t.Errorf("Expected events %v, got %v", expectedKinds, kinds)
+	}
+	expectedPositions := []int{1, 1, 1, 2, 2, 2, 2, 2}
+	if !reflect.DeepEqual(positions, expectedPositions) {
+		t.Errorf("Expected positions %v, got %v", expectedPositions, positions)
+	}
+}
@@ -0,0 +1,129 @@
+package gitgrab
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Pattern matches repository names. Patterns wrapped in slashes, such as
+// /^api-.*$/, are regular expressions; anything else is a case-insensitive
+// glob like "api-*".
+type Pattern struct {
+	raw string
+	re  *regexp.Regexp
+}
+
+func ParsePattern(s string) (Pattern, error) {
+	if len(s) > 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+		if err != nil {
+			return Pattern{}, fmt.Errorf("invalid pattern %s: %v", s, err)
+		}
+		return Pattern{raw: s, re: re}, nil
+	}
+
+	if _, err := path.Match(s, ""); err != nil {
+		return Pattern{}, fmt.Errorf("invalid pattern %s: %v", s, err)
+	}
+	return Pattern{raw: s}, nil
+}
+
+// ParsePatterns parses each value with ParsePattern
+func ParsePatterns(values []string) ([]Pattern, error) {
+	patterns := make([]Pattern, 0, len(values))
+	for _, value := range values {
+		p, err := ParsePattern(value)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+func (p Pattern) String() string {
+	return p.raw
+}
+
+func (p Pattern) Match(name RepositoryName) bool {
+	if p.re != nil {
+		return p.re.MatchString(name.String())
+	}
+	matched, _ := path.Match(strings.ToLower(p.raw), strings.ToLower(name.String()))
+	return matched
+}
+
+func matchAny(patterns []Pattern, name RepositoryName) bool {
+	for _, p := range patterns {
+		if p.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(v, s)
+	})
+}
+
+// Filter selects the subset of repositories to sync. The zero value matches
+// every repository; each populated field narrows the selection further.
+type Filter struct {
+	// Include keeps only repositories matching at least one pattern
+	Include []Pattern
+	// Exclude drops repositories matching any pattern
+	Exclude    []Pattern
+	NoForks    bool
+	NoArchived bool
+	// Topics keeps repositories tagged with at least one of the topics
+	Topics []string
+	// Languages keeps repositories whose primary language is listed
+	Languages []string
+	// Visibility keeps repositories with one of the listed visibilities
+	Visibility []Visibility
+}
+
+// Match reports whether repo passes every criterion of the filter
+func (f Filter) Match(repo Repository) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, repo.Name) {
+		return false
+	}
+	if matchAny(f.Exclude, repo.Name) {
+		return false
+	}
+	if f.NoForks && repo.Fork {
+		return false
+	}
+	if f.NoArchived && repo.Archived {
+		return false
+	}
+	if len(f.Topics) > 0 && !slices.ContainsFunc(repo.Topics, func(topic string) bool {
+		return containsFold(f.Topics, topic)
+	}) {
+		return false
+	}
+	if len(f.Languages) > 0 && !containsFold(f.Languages, repo.Language) {
+		return false
+	}
+	if len(f.Visibility) > 0 && !slices.Contains(f.Visibility, repo.EffectiveVisibility()) {
+		return false
+	}
+	return true
+}
+
+// Apply returns the repositories that match the filter, preserving order
+func (f Filter) Apply(repos []Repository) []Repository {
+	var selected []Repository
+	for _, repo := range repos {
+		if f.Match(repo) {
+			selected = append(selected, repo)
+		}
+	}
+	return selected
+}
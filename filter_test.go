@@ -0,0 +1,106 @@
+package gitgrab
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func mustPatterns(t *testing.T, values ...string) []Pattern {
+	t.Helper()
+	patterns, err := ParsePatterns(values)
+	if err != nil {
+		t.Fatalf("Failed to parse patterns: %v", err)
+	}
+	return patterns
+}
+
+func TestPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"api-*", "api-gateway", true},
+		{"api-*", "API-Gateway", true},
+		{"api-*", "web-api", false},
+		{"/^web-(ui|api)$/", "web-api", true},
+		{"/^web-(ui|api)$/", "web-apis", false},
+		{"tool?", "tools", true},
+	}
+
+	for _, tt := range tests {
+		p, err := ParsePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("Failed to parse pattern %s: %v", tt.pattern, err)
+		}
+		if actual := p.Match(RepositoryName(tt.name)); actual != tt.expected {
+			t.Errorf("Pattern %s matching %s: expected %v, got %v", tt.pattern, tt.name, tt.expected, actual)
+		}
+	}
+}
+
+func TestParsePattern_Invalid(t *testing.T) {
+	for _, value := range []string{"/([a-z/", "[unterminated"} {
+		if _, err := ParsePattern(value); err == nil {
+			t.Errorf("Expected error for pattern %s, got none", value)
+		}
+	}
+}
+
+func TestFilter_Apply(t *testing.T) {
+	repos := []Repository{
+		{Name: "api-gateway", Language: "Go", Topics: []string{"backend"}, Visibility: VisibilityPrivate},
+		{Name: "api-legacy", Language: "Java", Archived: true, Visibility: VisibilityPrivate},
+		{Name: "web-ui", Language: "TypeScript", Topics: []string{"frontend"}, Visibility: VisibilityPublic},
+		{Name: "api-fork", Language: "Go", Fork: true, Topics: []string{"backend"}},
+		{Name: "docs", Private: true},
+	}
+
+	tests := []struct {
+		name     string
+		filter   Filter
+		expected []RepositoryName
+	}{
+		{"zero value matches all", Filter{}, []RepositoryName{"api-gateway", "api-legacy", "web-ui", "api-fork", "docs"}},
+		{"include glob", Filter{Include: mustPatterns(t, "api-*")}, []RepositoryName{"api-gateway", "api-legacy", "api-fork"}},
+		{"exclude wins over include", Filter{Include: mustPatterns(t, "api-*"), Exclude: mustPatterns(t, "/legacy/")}, []RepositoryName{"api-gateway", "api-fork"}},
+		{"no forks or archived", Filter{NoForks: true, NoArchived: true}, []RepositoryName{"api-gateway", "web-ui", "docs"}},
+		{"topic", Filter{Topics: []string{"Backend"}}, []RepositoryName{"api-gateway", "api-fork"}},
+		{"language", Filter{Languages: []string{"go", "typescript"}}, []RepositoryName{"api-gateway", "web-ui", "api-fork"}},
+		{"visibility falls back to private flag", Filter{Visibility: []Visibility{VisibilityPrivate}}, []RepositoryName{"api-gateway", "api-legacy", "docs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := tt.filter.Apply(repos)
+			if len(selected) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %d repositories", tt.expected, len(selected))
+			}
+			for i, repo := range selected {
+				if repo.Name != tt.expected[i] {
+					t.Errorf("Expected %s at position %d, got %s", tt.expected[i], i, repo.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestRepository_DecodesFilterFields(t *testing.T) {
+	data := `{"name": "repo", "fork": true, "archived": true, "topics": ["cli", "go"],
+		"language": "Go", "visibility": "internal", "pushed_at": "2024-05-01T12:00:00Z"}`
+
+	var repo Repository
+	if err := json.Unmarshal([]byte(data), &repo); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !repo.Fork || !repo.Archived {
+		t.Error("Expected fork and archived flags to be decoded")
+	}
+	if len(repo.Topics) != 2 || repo.Language != "Go" || repo.Visibility != VisibilityInternal {
+		t.Errorf("Unexpected decoded repository: %+v", repo)
+	}
+	if repo.PushedAt.IsZero() {
+		t.Error("Expected pushed_at to be decoded")
+	}
+}
@@ -0,0 +1,81 @@
+package gitgrab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// GitRunner runs git commands. dir is the working directory of the command
+// (the current directory when empty), env holds variables added to the
+// environment, and the command's standard output is returned. A command
+// still running when ctx is done is stopped and ctx's error returned.
+type GitRunner interface {
+	Run(ctx context.Context, dir string, env []string, args ...string) (string, error)
+}
+
+// gitStopGrace is how long git may take to clean up after being interrupted
+// before it is killed
+const gitStopGrace = 10 * time.Second
+
+// ExecGitRunner runs commands with the git executable found in PATH
+type ExecGitRunner struct{}
+
+func (ExecGitRunner) Run(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	// Interrupt rather than kill, so git can remove lock files and
+	// partial clones itself
+	cmd.Cancel = func() error {
+		return interruptProcess(cmd)
+	}
+	cmd.WaitDelay = gitStopGrace
+
+	output, err := cmd.Output()
+	if err != nil && ctx.Err() != nil {
+		return string(output), ctx.Err()
+	}
+	// git explains failures on the last line of its error output
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if message := lastLine(string(exitErr.Stderr)); message != "" {
+			err = fmt.Errorf("%w: %s", err, message)
+		}
+	}
+	return string(output), err
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+func (c CloneConfig) git() GitRunner {
+	if c.Git == nil {
+		return ExecGitRunner{}
+	}
+	return c.Git
+}
+
+// Git backends accepted by NewGitRunner
+const (
+	BackendGit   = "git"
+	BackendGoGit = "go-git"
+)
+
+// NewGitRunner returns the runner for a backend name
+func NewGitRunner(backend string) (GitRunner, error) {
+	switch strings.ToLower(backend) {
+	case "", BackendGit:
+		return ExecGitRunner{}, nil
+	case BackendGoGit:
+		return GoGitRunner{}, nil
+	default:
+		return nil, fmt.Errorf("invalid git backend: %s", backend)
+	}
+}
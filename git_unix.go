@@ -0,0 +1,16 @@
+//go:build !windows
+
+package gitgrab
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// interruptProcess sends SIGINT to git. git stays in gitgrab's process
+// group, so ssh can still prompt for a passphrase or host key on the
+// terminal; Ctrl-C there reaches git and its helpers directly, and on a
+// timeout git stops the helpers it spawned itself.
+func interruptProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Signal(syscall.SIGINT)
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package gitgrab
+
+import "os/exec"
+
+// interruptProcess kills git; Windows has no SIGINT to send to a process
+func interruptProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -1,14 +1,16 @@
 package gitgrab
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
 // CloneMethod represents the method used to clone repositories
@@ -110,6 +112,36 @@ func (r RepositoryName) IsValid() bool {
 	return len(s) > 0 && !strings.ContainsAny(s, " /\\")
 }
 
+// DefaultGitHost is the git host used when none is configured
+const DefaultGitHost GitHost = "github.com"
+
+// DefaultAPIBaseURL is the REST API endpoint for github.com
+const DefaultAPIBaseURL = "https://api.github.com"
+
+// GitHost represents the hostname serving git repositories, either
+// github.com or a GitHub Enterprise Server instance
+type GitHost string
+
+func (h GitHost) String() string {
+	if h == "" {
+		return string(DefaultGitHost)
+	}
+	return string(h)
+}
+
+func (h GitHost) IsDefault() bool {
+	return h == "" || h == DefaultGitHost
+}
+
+// APIBaseURL returns the REST API endpoint for the host. GitHub Enterprise
+// Server exposes the API under /api/v3 on the same hostname.
+func (h GitHost) APIBaseURL() string {
+	if h.IsDefault() {
+		return DefaultAPIBaseURL
+	}
+	return "https://" + h.String() + "/api/v3"
+}
+
 // BranchName represents a git branch name
 type BranchName string
 
@@ -122,6 +154,28 @@ func (b BranchName) IsDefault() bool {
 	return s == "main" || s == "master"
 }
 
+// Visibility represents a repository's visibility as reported by the API
+type Visibility string
+
+const (
+	VisibilityPublic   Visibility = "public"
+	VisibilityPrivate  Visibility = "private"
+	VisibilityInternal Visibility = "internal"
+)
+
+func (v Visibility) String() string {
+	return string(v)
+}
+
+func ParseVisibility(s string) (Visibility, error) {
+	switch v := Visibility(strings.ToLower(s)); v {
+	case VisibilityPublic, VisibilityPrivate, VisibilityInternal:
+		return v, nil
+	default:
+		return "", fmt.Errorf("invalid visibility: %s", s)
+	}
+}
+
 // CloneConfig groups all parameters needed for cloning
 type CloneConfig struct {
 	Repository   Repository
@@ -129,14 +183,94 @@ type CloneConfig struct {
 	Token        GitHubToken
 	Organization OrganizationName
 	Method       CloneMethod
+	// Host is the git host to clone from; defaults to github.com
+	Host GitHost
+	// Layout is the layout name or template placing the repository below
+	// TargetDir; defaults to FlatTemplate
+	Layout string
+	// Branch overrides the repository's default branch as the branch that
+	// is checked out on clone and pulled on update
+	Branch BranchName
+	// Reporter receives progress events; defaults to a ConsoleReporter on
+	// os.Stdout when nil
+	Reporter Reporter
+	// Git runs git commands; defaults to ExecGitRunner when nil
+	Git GitRunner
+	// RemoteMismatch is the policy for an existing repository whose origin
+	// remote points at another repository; defaults to RemoteMismatchSkip
+	RemoteMismatch string
+	// FixRemotes rewrites the origin remote of existing repositories to the
+	// URL for Method, so switching between SSH and HTTP applies to
+	// repositories cloned before
+	FixRemotes bool
+	// UpdateStrategy decides how the tracked branch is pulled; defaults to
+	// UpdateFFOnly
+	UpdateStrategy string
+	// AllBranches also fast-forwards local branches other than the checked
+	// out one after updating
+	AllBranches bool
+	// Previous is where the repository was synced last time, found by its
+	// ID in the State. A repository renamed or transferred since, or moved
+	// to another directory by a layout change, is moved to RepoPath.
+	Previous *RepoState
+}
+
+// trackedBranch returns the branch kept up to date with git pull
+func (c CloneConfig) trackedBranch() BranchName {
+	if c.Branch != "" {
+		return c.Branch
+	}
+	return c.Repository.DefaultBranch
+}
+
+func (c CloneConfig) reporter() Reporter {
+	if c.Reporter == nil {
+		return NewConsoleReporter(os.Stdout)
+	}
+	return c.Reporter
+}
+
+// report sends an event about the configured repository
+func (c CloneConfig) report(e Event) {
+	e.Repository = c.Repository
+	c.reporter().Report(e)
+}
+
+// RepositoryOwner is the account, user or organization, owning a repository
+type RepositoryOwner struct {
+	Login string `json:"login"`
 }
 
 type Repository struct {
-	Name          RepositoryName `json:"name"`
-	CloneURL      HTTPURL        `json:"clone_url"`
-	SSHURL        SSHURL         `json:"ssh_url"`
-	Private       bool           `json:"private"`
-	DefaultBranch BranchName     `json:"default_branch"`
+	ID            int64           `json:"id"`
+	Name          RepositoryName  `json:"name"`
+	FullName      string          `json:"full_name"`
+	Owner         RepositoryOwner `json:"owner"`
+	CloneURL      HTTPURL         `json:"clone_url"`
+	SSHURL        SSHURL          `json:"ssh_url"`
+	Private       bool            `json:"private"`
+	DefaultBranch BranchName      `json:"default_branch"`
+	Fork          bool            `json:"fork"`
+	Archived      bool            `json:"archived"`
+	Topics        []string        `json:"topics"`
+	Language      string          `json:"language"`
+	Visibility    Visibility      `json:"visibility"`
+	PushedAt      time.Time       `json:"pushed_at"`
+	// Permissions is only populated by endpoints that report the caller's
+	// access, such as team repository listings
+	Permissions RepositoryPermissions `json:"permissions"`
+}
+
+// EffectiveVisibility returns the repository's visibility, deriving it from
+// the private flag for API versions that don't report it
+func (r Repository) EffectiveVisibility() Visibility {
+	if r.Visibility != "" {
+		return r.Visibility
+	}
+	if r.Private {
+		return VisibilityPrivate
+	}
+	return VisibilityPublic
 }
 
 type HTTPClient interface {
@@ -144,26 +278,73 @@ type HTTPClient interface {
 }
 
 type GitHubClient struct {
-	token  GitHubToken
-	client HTTPClient
+	token           GitHubToken
+	baseURL         string
+	client          HTTPClient
+	pageConcurrency int
+	requestTimeout  time.Duration
+
+	maxWait    time.Duration
+	maxRetries int
+	notify     func(RetryNotice)
+	now        func() time.Time
+	sleep      func(context.Context, time.Duration) error
+
+	mu        sync.Mutex
+	rateLimit RateLimit
 }
 
-func NewGitHubClient(token GitHubToken) *GitHubClient {
-	return &GitHubClient{
-		token:  token,
-		client: &http.Client{},
+// ClientOption configures optional GitHubClient behaviour
+type ClientOption func(*GitHubClient)
+
+// WithBaseURL points the client at a different REST API endpoint, such as
+// https://github.example.com/api/v3 for GitHub Enterprise Server
+func WithBaseURL(baseURL string) ClientOption {
+	return func(gc *GitHubClient) {
+		gc.baseURL = strings.TrimSuffix(baseURL, "/")
 	}
 }
 
-func NewGitHubClientWithHTTPClient(token GitHubToken, client HTTPClient) *GitHubClient {
-	return &GitHubClient{
-		token:  token,
-		client: client,
+// WithPageConcurrency fetches up to n pages at once when the total number of
+// pages is known from the first response's Link header. Values below 2 keep
+// pagination sequential.
+func WithPageConcurrency(n int) ClientOption {
+	return func(gc *GitHubClient) {
+		gc.pageConcurrency = n
 	}
 }
 
-func (gc *GitHubClient) makeRequest(url string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", url, nil)
+// WithRequestTimeout limits how long a single API request may take,
+// including reading its response. Zero means no limit.
+func WithRequestTimeout(d time.Duration) ClientOption {
+	return func(gc *GitHubClient) {
+		gc.requestTimeout = d
+	}
+}
+
+func NewGitHubClient(token GitHubToken, opts ...ClientOption) *GitHubClient {
+	return NewGitHubClientWithHTTPClient(token, &http.Client{}, opts...)
+}
+
+func NewGitHubClientWithHTTPClient(token GitHubToken, client HTTPClient, opts ...ClientOption) *GitHubClient {
+	gc := &GitHubClient{
+		token:      token,
+		baseURL:    DefaultAPIBaseURL,
+		client:     client,
+		maxWait:    DefaultMaxRateLimitWait,
+		maxRetries: DefaultMaxRetries,
+		now:        time.Now,
+		sleep:      sleepContext,
+	}
+	for _, opt := range opts {
+		opt(gc)
+	}
+	return gc
+}
+
+// doRequest performs a single authenticated GET request against the API
+func (gc *GitHubClient) doRequest(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -172,139 +353,315 @@ func (gc *GitHubClient) makeRequest(url string) (*http.Response, error) {
 	req.Header.Set("Accept", "application/vnd.github.v3+json")
 	req.Header.Set("User-Agent", "GitHub-Repo-Cloner")
 
-	return gc.client.Do(req)
+	if gc.requestTimeout <= 0 {
+		return gc.client.Do(req)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, gc.requestTimeout)
+	resp, err := gc.client.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// The timeout also covers reading the body, so it ends when the body
+	// is closed
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
 }
 
 func (gc *GitHubClient) FetchAllRepos(orgName OrganizationName) ([]Repository, error) {
-	var allRepos []Repository
-	page := 1
-	perPage := 100
-
-	for {
-		url := fmt.Sprintf("https://api.github.com/orgs/%s/repos?page=%d&per_page=%d&type=all", orgName, page, perPage)
-		
-		resp, err := gc.makeRequest(url)
-		if err != nil {
-			return nil, fmt.Errorf("failed to make request: %v", err)
-		}
-		defer resp.Body.Close()
+	return gc.FetchAllReposContext(context.Background(), orgName)
+}
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return nil, fmt.Errorf("API request failed: %s - %s", resp.Status, string(body))
-		}
+// FetchAllReposContext is like FetchAllRepos but stops when ctx is done
+func (gc *GitHubClient) FetchAllReposContext(ctx context.Context, orgName OrganizationName) ([]Repository, error) {
+	url := fmt.Sprintf("%s/orgs/%s/repos?per_page=%d&type=all", gc.baseURL, orgName, perPage)
+	return fetchPages[Repository](ctx, gc, url)
+}
+
+func getCurrentBranch(ctx context.Context, git GitRunner, repoPath string) (string, error) {
+	output, err := git.Run(ctx, repoPath, nil, "branch", "--show-current")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(output), nil
+}
 
-		var repos []Repository
-		if err := json.NewDecoder(resp.Body).Decode(&repos); err != nil {
-			return nil, fmt.Errorf("failed to decode response: %v", err)
+// cloneURL returns the remote URL for the configured clone method. HTTP URLs
+// never carry credentials; the token is supplied by runGit instead.
+func cloneURL(config CloneConfig) string {
+	if config.Method == CloneMethodSSH {
+		return config.Repository.SSHURL.String()
+	}
+	if config.Repository.CloneURL != "" {
+		return config.Repository.CloneURL.String()
+	}
+	return fmt.Sprintf("https://%s/%s/%s.git", config.Host, config.Organization, config.Repository.Name)
+}
+
+func CloneRepo(config CloneConfig) error {
+	return SyncRepoContext(context.Background(), config).Err
+}
+
+// CloneRepoContext is like CloneRepo but stops git when ctx is done
+func CloneRepoContext(ctx context.Context, config CloneConfig) error {
+	return SyncRepoContext(ctx, config).Err
+}
+
+// SyncRepo clones or updates a single repository like CloneRepo and reports
+// what was done
+func SyncRepo(config CloneConfig) SyncResult {
+	return SyncRepoContext(context.Background(), config)
+}
+
+// SyncRepoContext is like SyncRepo but stops git when ctx is done. Clones
+// are made in a staging directory and only moved into place once complete,
+// so an interrupted or failed clone never leaves a broken repository behind.
+func SyncRepoContext(ctx context.Context, config CloneConfig) SyncResult {
+	start := time.Now()
+	plan := planRepo(ctx, config)
+
+	result := SyncResult{
+		Repository: config.Repository,
+		Path:       plan.Path,
+		Action:     plan.Action,
+	}
+	if plan.Action == ActionSkip {
+		result.Reason = plan.Reason
+	}
+	// Only a working repository has a HEAD of its own; git would report the
+	// HEAD of an enclosing repository for anything else
+	updating := plan.Action == ActionPull || plan.Action == ActionFetch
+	if updating {
+		current := plan.Path
+		if plan.MovedFrom != "" {
+			current = plan.MovedFrom
 		}
+		result.OldHead, _ = headRevision(ctx, config.git(), current)
+	}
+	// Steps taken before a failure are still reported
+	result.Err = applyPlan(ctx, config, &plan)
+	result.Action = plan.Action
+	result.MovedFrom = plan.MovedFrom
+	result.RenamedFrom = plan.RenamedFrom
+	if result.Err == nil && updating && config.AllBranches {
+		result.UpdatedBranches, result.DivergedBranches = updateBranches(ctx, config, plan.Path)
+	}
+	if result.Err == nil && plan.Action != ActionSkip && config.Repository.Archived {
+		freezeArchived(config, plan.Path)
+	}
+	if updating || (result.Err == nil && plan.Action != ActionSkip) {
+		result.NewHead, _ = headRevision(ctx, config.git(), plan.Path)
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// stagingPath returns the directory a repository is cloned into before it is
+// moved to repoPath. It is a hidden sibling, so the final rename stays on
+// the same filesystem.
+func stagingPath(repoPath string) string {
+	return filepath.Join(filepath.Dir(repoPath), "."+filepath.Base(repoPath)+".gitgrab-clone")
+}
 
-		if len(repos) == 0 {
-			break
+// brokenPath returns an unused hidden sibling of repoPath that a broken
+// repository is moved to before it is replaced
+func brokenPath(repoPath string) string {
+	base := filepath.Join(filepath.Dir(repoPath), "."+filepath.Base(repoPath)+".gitgrab-broken")
+	path := base
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+			return path
 		}
+		path = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// cloneInto clones the repository into a staging directory and moves it to
+// repoPath once the clone is complete. Whatever repoPath held, unless it was
+// an empty directory, is moved aside rather than deleted; its new location
+// is returned.
+func cloneInto(ctx context.Context, config CloneConfig, repoPath string) (string, error) {
+	staging := stagingPath(repoPath)
+	// A previous run may have been killed before it could clean up
+	if err := os.RemoveAll(staging); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(repoPath), 0755); err != nil {
+		return "", err
+	}
 
-		allRepos = append(allRepos, repos...)
-		page++
+	args := []string{"clone"}
+	if config.Branch != "" {
+		args = append(args, "--branch", config.Branch.String())
+	}
+	if _, err := runGit(ctx, config, "", append(args, cloneURL(config), staging)...); err != nil {
+		os.RemoveAll(staging)
+		return "", err
 	}
 
-	return allRepos, nil
+	var aside string
+	if err := os.Remove(repoPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		aside = brokenPath(repoPath)
+		if err := os.Rename(repoPath, aside); err != nil {
+			os.RemoveAll(staging)
+			return "", err
+		}
+	}
+	if err := os.Rename(staging, repoPath); err != nil {
+		os.RemoveAll(staging)
+		return aside, err
+	}
+	return aside, nil
 }
 
-func getCurrentBranch(repoPath string) (string, error) {
-	cmd := exec.Command("git", "-C", repoPath, "branch", "--show-current")
-	output, err := cmd.Output()
+// headRevision returns the commit checked out in a repository
+func headRevision(ctx context.Context, git GitRunner, repoPath string) (string, error) {
+	output, err := git.Run(ctx, repoPath, nil, "rev-parse", "--verify", "--quiet", "HEAD")
 	if err != nil {
 		return "", err
 	}
-	
-	return strings.TrimSpace(string(output)), nil
+	return strings.TrimSpace(output), nil
 }
 
-func CloneRepo(config CloneConfig) error {
-	repoPath := filepath.Join(config.TargetDir, config.Repository.Name.String())
-	
-	// Check if directory already exists
-	if _, err := os.Stat(repoPath); err == nil {
-		fmt.Printf("  Directory %s already exists, updating...\n", config.Repository.Name)
-		
-		// Use default branch from the repository data (already fetched from API)
-		defaultBranch := config.Repository.DefaultBranch
-		if defaultBranch.String() == "" {
-			fmt.Printf("  Warning: No default branch information for %s\n", config.Repository.Name)
-			fmt.Printf("  Performing git fetch instead...\n")
-			
-			// Fallback to git fetch
-			cmd := exec.Command("git", "-C", repoPath, "fetch")
-			cmd.Stdout = nil
-			cmd.Stderr = nil
-			if err := cmd.Run(); err != nil {
-				return fmt.Errorf("failed to fetch %s: %v", config.Repository.Name, err)
-			}
-			fmt.Printf("  ✓ Fetched latest changes for %s\n", config.Repository.Name)
-			return nil
+// applyPlan performs the clone, pull or fetch decided by PlanRepo. The plan
+// is updated to what was done: a move or branch rename that did not happen
+// is cleared, and a pull that turns out not to apply becomes a fetch.
+func applyPlan(ctx context.Context, config CloneConfig, plan *PlanEntry) error {
+	repoPath := plan.Path
+	event := func(kind EventKind) Event {
+		return Event{Kind: kind, Path: repoPath, Action: plan.Action}
+	}
+
+	switch plan.Action {
+	case ActionSkip:
+		if plan.err != nil {
+			return fmt.Errorf("cannot sync %s: %s: %w", config.Repository.Name, repoPath, plan.err)
 		}
-		
-		// Get the current branch
-		currentBranch, err := getCurrentBranch(repoPath)
-		if err != nil {
-			fmt.Printf("  Warning: Could not determine current branch for %s: %v\n", config.Repository.Name, err)
-			fmt.Printf("  Performing git fetch instead...\n")
-			
-			// Fallback to git fetch
-			cmd := exec.Command("git", "-C", repoPath, "fetch")
-			cmd.Stdout = nil
-			cmd.Stderr = nil
-			if err := cmd.Run(); err != nil {
-				return fmt.Errorf("failed to fetch %s: %v", config.Repository.Name, err)
-			}
-			fmt.Printf("  ✓ Fetched latest changes for %s\n", config.Repository.Name)
-			return nil
+		e := event(EventSkipped)
+		e.Message = plan.Reason
+		config.report(e)
+		return nil
+	case ActionReclone:
+		e := event(EventRecloning)
+		e.Message = plan.Reason
+		config.report(e)
+		fallthrough
+	case ActionClone:
+		aside, err := cloneInto(ctx, config, repoPath)
+		if aside != "" {
+			e := event(EventWarning)
+			e.Message = fmt.Sprintf("Moved the broken repository %s to %s", config.Repository.Name, aside)
+			config.report(e)
 		}
-		
-		// Perform git pull if on default branch, git fetch otherwise
-		if BranchName(currentBranch) == defaultBranch {
-			fmt.Printf("  On default branch (%s), performing git pull...\n", defaultBranch)
-			cmd := exec.Command("git", "-C", repoPath, "pull")
-			cmd.Stdout = nil
-			cmd.Stderr = nil
-			if err := cmd.Run(); err != nil {
-				return fmt.Errorf("failed to pull %s: %v", config.Repository.Name, err)
-			}
-			fmt.Printf("  ✓ Pulled latest changes for %s\n", config.Repository.Name)
-		} else {
-			fmt.Printf("  On branch %s (not default), performing git fetch...\n", currentBranch)
-			cmd := exec.Command("git", "-C", repoPath, "fetch")
-			cmd.Stdout = nil
-			cmd.Stderr = nil
-			if err := cmd.Run(); err != nil {
-				return fmt.Errorf("failed to fetch %s: %v", config.Repository.Name, err)
-			}
-			fmt.Printf("  ✓ Fetched latest changes for %s\n", config.Repository.Name)
+		if err != nil {
+			return fmt.Errorf("failed to clone %s: %w", config.Repository.Name, err)
 		}
-		
+		config.report(event(EventCloned))
 		return nil
 	}
 
-	// Prepare clone URL based on clone method
-	var cloneURL string
-	if config.Method == CloneMethodSSH {
-		cloneURL = config.Repository.SSHURL.String()
-	} else {
-		if config.Repository.Private {
-			cloneURL = fmt.Sprintf("https://%s@github.com/%s/%s.git", config.Token, config.Organization, config.Repository.Name)
-		} else {
-			cloneURL = config.Repository.CloneURL.String()
+	if plan.MovedFrom != "" {
+		err := os.MkdirAll(filepath.Dir(repoPath), 0755)
+		if err == nil {
+			err = os.Rename(plan.MovedFrom, repoPath)
+		}
+		if err != nil {
+			plan.MovedFrom = ""
+			return fmt.Errorf("failed to move %s: %w", config.Repository.Name, err)
+		}
+		e := event(EventMoved)
+		e.Message = plan.MovedFrom
+		config.report(e)
+	}
+
+	config.report(event(EventUpdating))
+
+	if unarchived(config) {
+		if err := setWritable(repoPath, true); err != nil {
+			e := event(EventWarning)
+			e.Message = fmt.Sprintf("Could not make %s writable again: %v", config.Repository.Name, err)
+			config.report(e)
 		}
 	}
 
-	// Execute git clone
-	cmd := exec.Command("git", "clone", cloneURL, repoPath)
-	cmd.Stdout = nil // Suppress output
-	cmd.Stderr = nil // Suppress error output
+	// Older versions embedded the token in the remote URL
+	if scrubbed, err := scrubRemoteCredentials(ctx, config.git(), repoPath); err != nil {
+		e := event(EventWarning)
+		e.Message = fmt.Sprintf("Could not inspect origin remote for %s: %v", config.Repository.Name, err)
+		config.report(e)
+	} else if scrubbed {
+		config.report(event(EventRemoteScrubbed))
+	}
+
+	if plan.Origin != "" {
+		if _, err := config.git().Run(ctx, repoPath, nil, "remote", "set-url", "origin", cloneURL(config)); err != nil {
+			return fmt.Errorf("failed to update origin remote of %s: %w", config.Repository.Name, err)
+		}
+		e := event(EventRemoteRewritten)
+		e.Message = plan.Origin
+		config.report(e)
+	}
+
+	if plan.RenamedFrom != "" {
+		previous := plan.RenamedFrom
+		renamed, err := migrateDefaultBranch(ctx, config, repoPath, previous, plan.TrackedBranch.String())
+		if !renamed {
+			plan.RenamedFrom = ""
+		}
+		if err != nil {
+			return fmt.Errorf("failed to migrate %s from %s to %s: %w", config.Repository.Name, previous, plan.TrackedBranch, err)
+		}
+		kind := EventBranchRenamed
+		if !renamed {
+			kind = EventDefaultBranchChanged
+			// The old default branch is still upstream, so a clone on it
+			// stays there and is only fetched
+			if plan.Action == ActionPull && plan.CurrentBranch != plan.TrackedBranch.String() {
+				plan.Action = ActionFetch
+				plan.Reason = fmt.Sprintf("on branch %s, not %s", plan.CurrentBranch, plan.TrackedBranch)
+			}
+		}
+		e := event(kind)
+		e.Branch = plan.TrackedBranch.String()
+		e.Message = previous
+		config.report(e)
+	}
 
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to clone %s: %v", config.Repository.Name, err)
+	// Perform git pull if on default branch, git fetch otherwise
+	if plan.Action == ActionPull {
+		e := event(EventPulling)
+		e.Branch = plan.TrackedBranch.String()
+		config.report(e)
+		if _, err := runGit(ctx, config, repoPath, pullArgs(config.UpdateStrategy)...); err != nil {
+			if rebases(config.UpdateStrategy) {
+				// Leave the repository as it was rather than mid-rebase
+				config.git().Run(context.WithoutCancel(ctx), repoPath, nil, "rebase", "--abort")
+			}
+			return fmt.Errorf("failed to pull %s: %w", config.Repository.Name, err)
+		}
+		config.report(event(EventPulled))
+		return nil
 	}
 
+	e := event(EventFetching)
+	e.Branch = plan.CurrentBranch
+	e.Message = plan.Warning
+	config.report(e)
+	if _, err := runGit(ctx, config, repoPath, "fetch"); err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", config.Repository.Name, err)
+	}
+	config.report(event(EventFetched))
 	return nil
-}
\ No newline at end of file
+}
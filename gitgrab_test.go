@@ -1,7 +1,9 @@
 package gitgrab
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -9,6 +11,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 type mockHTTPClient struct {
@@ -49,7 +52,7 @@ func TestGitHubClient_makeRequest(t *testing.T) {
 	defer server.Close()
 
 	client := NewGitHubClient(GitHubToken("test-token"))
-	resp, err := client.makeRequest(server.URL)
+	resp, err := client.makeRequest(context.Background(), server.URL)
 	
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
@@ -189,15 +192,17 @@ func TestCloneRepo_URLGeneration_PrivateHTTP(t *testing.T) {
 		DefaultBranch: BranchName("main"),
 	}
 	
-	token := "token123"
-	orgName := "testorg"
-	expectedURL := "https://token123@github.com/testorg/private-repo.git"
-	
-	if repo.Private {
-		actualURL := "https://" + token + "@github.com/" + orgName + "/" + repo.Name.String() + ".git"
-		if actualURL != expectedURL {
-			t.Errorf("Expected private repo HTTP URL %s, got %s", expectedURL, actualURL)
-		}
+	config := CloneConfig{
+		Repository:   repo,
+		Token:        GitHubToken("token123"),
+		Organization: OrganizationName("testorg"),
+		Method:       CloneMethodHTTP,
+	}
+
+	// The token must never be embedded in the remote URL
+	expectedURL := "https://github.com/test/private-repo.git"
+	if actualURL := cloneURL(config); actualURL != expectedURL {
+		t.Errorf("Expected private repo HTTP URL %s, got %s", expectedURL, actualURL)
 	}
 }
 
@@ -247,7 +252,7 @@ func TestGetCurrentBranch(t *testing.T) {
 	exec.Command("git", "-C", tempDir, "commit", "-m", "Initial commit").Run()
 	
 	// Test getting current branch
-	branch, err := getCurrentBranch(tempDir)
+	branch, err := getCurrentBranch(context.Background(), ExecGitRunner{}, tempDir)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -314,10 +319,10 @@ func TestCloneRepo_ExistingDirectory_GitPullFetch(t *testing.T) {
 
 func TestCloneRepo_CloneMethodSSH(t *testing.T) {
 	tests := []struct {
-		name           string
-		repo           Repository
-		cloneMethod    string
-		expectedSSHURL bool
+		name        string
+		repo        Repository
+		cloneMethod string
+		expectedURL string
 	}{
 		{
 			name: "Private repo with SSH method",
@@ -328,8 +333,8 @@ func TestCloneRepo_CloneMethodSSH(t *testing.T) {
 				Private:       true,
 				DefaultBranch: BranchName("main"),
 			},
-			cloneMethod:    "ssh",
-			expectedSSHURL: true,
+			cloneMethod: "ssh",
+			expectedURL: "git@github.com:test/private-repo.git",
 		},
 		{
 			name: "Private repo with HTTP method",
@@ -340,8 +345,9 @@ func TestCloneRepo_CloneMethodSSH(t *testing.T) {
 				Private:       true,
 				DefaultBranch: BranchName("main"),
 			},
-			cloneMethod:    "http",
-			expectedSSHURL: false,
+			cloneMethod: "http",
+			// The token is supplied by the credential helper, never the URL
+			expectedURL: "https://github.com/test/private-repo.git",
 		},
 		{
 			name: "Public repo with SSH method",
@@ -352,8 +358,8 @@ func TestCloneRepo_CloneMethodSSH(t *testing.T) {
 				Private:       false,
 				DefaultBranch: BranchName("main"),
 			},
-			cloneMethod:    "ssh",
-			expectedSSHURL: true, // Public repos now respect method flag
+			cloneMethod: "ssh",
+			expectedURL: "git@github.com:test/public-repo.git", // Public repos now respect method flag
 		},
 		{
 			name: "Public repo with HTTP method",
@@ -364,31 +370,182 @@ func TestCloneRepo_CloneMethodSSH(t *testing.T) {
 				Private:       false,
 				DefaultBranch: BranchName("main"),
 			},
-			cloneMethod:    "http",
-			expectedSSHURL: false,
+			cloneMethod: "http",
+			expectedURL: "https://github.com/test/public-repo.git",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var expectedURL string
-			if tt.cloneMethod == "ssh" {
-				expectedURL = tt.repo.SSHURL.String()
-			} else {
-				if tt.repo.Private {
-					expectedURL = "https://token@github.com/testorg/" + tt.repo.Name.String() + ".git"
-				} else {
-					expectedURL = tt.repo.CloneURL.String()
-				}
+			method, err := ParseCloneMethod(tt.cloneMethod)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
 			}
-			
-			// We can't actually test git clone without git being available,
-			// but we can verify the URL generation logic
-			if tt.cloneMethod == "ssh" && tt.repo.SSHURL.String() != expectedURL {
-				t.Errorf("Expected SSH URL %s, got %s", expectedURL, tt.repo.SSHURL)
-			} else if tt.cloneMethod == "http" && !tt.repo.Private && tt.repo.CloneURL.String() != expectedURL {
-				t.Errorf("Expected HTTP URL %s, got %s", expectedURL, tt.repo.CloneURL)
+
+			targetDir := t.TempDir()
+			git := &recordingGit{}
+			err = CloneRepo(CloneConfig{
+				Repository:   tt.repo,
+				TargetDir:    targetDir,
+				Token:        GitHubToken("token"),
+				Organization: OrganizationName("testorg"),
+				Method:       method,
+				Reporter:     ReporterFunc(func(Event) {}),
+				Git:          git,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			expected := "clone " + tt.expectedURL + " " + stagingPath(filepath.Join(targetDir, tt.repo.Name.String()))
+			if actual := git.calls[0].command(); actual != expected {
+				t.Errorf("Expected %q, got %q", expected, actual)
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestGitHost_APIBaseURL(t *testing.T) {
+	tests := []struct {
+		host     GitHost
+		expected string
+	}{
+		{GitHost(""), "https://api.github.com"},
+		{GitHost("github.com"), "https://api.github.com"},
+		{GitHost("github.example.com"), "https://github.example.com/api/v3"},
+	}
+
+	for _, tt := range tests {
+		if actual := tt.host.APIBaseURL(); actual != tt.expected {
+			t.Errorf("Expected API base URL %s for host %q, got %s", tt.expected, tt.host, actual)
+		}
+	}
+}
+
+func TestGitHubClient_FetchAllRepos_EnterpriseBaseURL(t *testing.T) {
+	var requestedURL string
+	mockClient := &mockHTTPClient{
+		doFunc: func(req *http.Request) (*http.Response, error) {
+			requestedURL = req.URL.String()
+			recorder := httptest.NewRecorder()
+			recorder.WriteHeader(http.StatusOK)
+			json.NewEncoder(recorder).Encode([]Repository{})
+			return recorder.Result(), nil
+		},
+	}
+
+	client := NewGitHubClientWithHTTPClient(GitHubToken("test-token"), mockClient, WithBaseURL("https://github.example.com/api/v3/"))
+	if _, err := client.FetchAllRepos(OrganizationName("testorg")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.HasPrefix(requestedURL, "https://github.example.com/api/v3/orgs/testorg/repos?") {
+		t.Errorf("Expected request against enterprise API, got %s", requestedURL)
+	}
+}
+
+func TestGitHubClient_RequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient(GitHubToken("test-token"), WithBaseURL(server.URL), WithRequestTimeout(50*time.Millisecond), WithMaxRetries(0))
+	_, err := client.FetchAllRepos(OrganizationName("testorg"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestSyncRepo_RepairsBrokenRepository(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "upstream")
+	initRepoOnBranch(t, source, "main")
+
+	targetDir := t.TempDir()
+	repoPath := filepath.Join(targetDir, "upstream")
+	// What an interrupted clone used to leave behind
+	os.MkdirAll(filepath.Join(repoPath, ".git", "objects"), 0755)
+
+	result := SyncRepo(CloneConfig{
+		Repository: Repository{Name: "upstream", CloneURL: HTTPURL(source), DefaultBranch: "main"},
+		TargetDir:  targetDir,
+		Method:     CloneMethodHTTP,
+		Reporter:   ReporterFunc(func(Event) {}),
+	})
+
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+	if result.Action != ActionReclone || result.NewHead == "" {
+		t.Errorf("Expected the repository to be recloned, got %+v", result)
+	}
+	if _, err := os.Stat(stagingPath(repoPath)); !os.IsNotExist(err) {
+		t.Errorf("Expected staging directory to be gone, got %v", err)
+	}
+	// The broken repository is kept aside rather than deleted
+	if _, err := os.Stat(filepath.Join(targetDir, ".upstream.gitgrab-broken", ".git", "objects")); err != nil {
+		t.Errorf("Expected the broken repository to be moved aside: %v", err)
+	}
+}
+
+func TestSyncRepo_KeepsRepositoryGitCannotInspect(t *testing.T) {
+	targetDir := t.TempDir()
+	repoPath := filepath.Join(targetDir, "api")
+	fakeRepository(repoPath)
+
+	// git refuses repositories owned by someone else, which says nothing
+	// about whether the repository is intact
+	git := &recordingGit{errors: map[string]error{
+		"rev-parse --show-toplevel": errors.New("exit status 128: fatal: detected dubious ownership in repository at '" + repoPath + "'"),
+	}}
+	config := CloneConfig{
+		Repository: Repository{Name: "api", DefaultBranch: "main"},
+		TargetDir:  targetDir,
+		Reporter:   ReporterFunc(func(Event) {}),
+		Git:        git,
+	}
+
+	if plan := PlanRepo(config); plan.Action != ActionSkip {
+		t.Errorf("Expected the repository to be left alone, got %+v", plan)
+	}
+	result := SyncRepo(config)
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "dubious ownership") {
+		t.Errorf("Expected the git error to be reported, got %v", result.Err)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, ".git", "HEAD")); err != nil {
+		t.Errorf("Expected the repository to be kept: %v", err)
+	}
+	for _, command := range git.commands() {
+		if strings.HasPrefix(command, "clone") {
+			t.Errorf("Expected no clone, got %v", git.commands())
+		}
+	}
+}
+
+func TestSyncRepo_LeavesOtherDirectoriesAlone(t *testing.T) {
+	targetDir := t.TempDir()
+	notes := filepath.Join(targetDir, "api", "notes.txt")
+	os.MkdirAll(filepath.Dir(notes), 0755)
+	os.WriteFile(notes, []byte("keep me"), 0644)
+
+	git := &recordingGit{}
+	result := SyncRepo(CloneConfig{
+		Repository: Repository{Name: "api", DefaultBranch: "main"},
+		TargetDir:  targetDir,
+		Reporter:   ReporterFunc(func(Event) {}),
+		Git:        git,
+	})
+
+	if !errors.Is(result.Err, ErrNotGitRepository) {
+		t.Errorf("Expected ErrNotGitRepository, got %v", result.Err)
+	}
+	if len(git.calls) != 0 {
+		t.Errorf("Expected no git commands, got %v", git.commands())
+	}
+	if _, err := os.Stat(notes); err != nil {
+		t.Errorf("Expected existing files to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,447 @@
+package gitgrab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+// ErrUnsupportedGitCommand is returned by GoGitRunner for git commands it
+// does not implement
+var ErrUnsupportedGitCommand = errors.New("git command not supported by the go-git backend")
+
+// GoGitRunner implements the git commands gitgrab uses with go-git, so no
+// git executable is needed: clone, fetch, fast-forward pull, status of
+// tracked files, current branch and HEAD lookup, listing and fast-forwarding
+// local branches, work tree detection, and reading and setting the origin
+// URL. Rebasing pulls are not supported.
+type GoGitRunner struct{}
+
+// goGitCommand is a git invocation with the credential settings added by
+// runGit separated from the command itself
+type goGitCommand struct {
+	ctx  context.Context
+	dir  string
+	args []string
+	// authHost is the host the token may be sent to, taken from the
+	// credential helper setting
+	authHost string
+	token    string
+}
+
+func (GoGitRunner) Run(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	c := goGitCommand{ctx: ctx, dir: dir}
+	for len(args) >= 2 && args[0] == "-c" {
+		if key, _, ok := strings.Cut(args[1], "="); ok && strings.HasPrefix(key, "credential.https://") {
+			c.authHost = strings.TrimSuffix(strings.TrimPrefix(key, "credential.https://"), ".helper")
+		}
+		args = args[2:]
+	}
+	for _, kv := range env {
+		if value, ok := strings.CutPrefix(kv, tokenEnvVar+"="); ok {
+			c.token = value
+		}
+	}
+	c.args = args
+
+	switch strings.Join(args, " ") {
+	case "branch --show-current":
+		return c.currentBranch()
+	case "rev-parse --verify --quiet HEAD":
+		return c.head()
+	case "rev-parse --show-toplevel":
+		return c.topLevel()
+	case "fetch":
+		return "", c.fetch()
+	case "pull", "pull --ff-only":
+		return "", c.pull()
+	case "status --porcelain --untracked-files=no":
+		return c.status()
+	case "for-each-ref --format=" + branchFormat + " refs/heads":
+		return c.branches()
+	case "remote get-url origin":
+		return c.originURL()
+	}
+	if len(args) > 0 && args[0] == "clone" {
+		return "", c.clone()
+	}
+	if len(args) == 4 && strings.Join(args[:3], " ") == "remote set-url origin" {
+		return "", c.setOriginURL(args[3])
+	}
+	if len(args) == 4 && args[0] == "update-ref" {
+		return "", c.updateRef(args[1], args[2], args[3])
+	}
+	return "", fmt.Errorf("%w: git %s", ErrUnsupportedGitCommand, strings.Join(args, " "))
+}
+
+func (c goGitCommand) open() (*git.Repository, error) {
+	dir := c.dir
+	if dir == "" {
+		dir = "."
+	}
+	return git.PlainOpen(dir)
+}
+
+// auth returns the token credentials for HTTPS remotes on the host the
+// credential helper was configured for, mirroring git's own behaviour
+func (c goGitCommand) auth(remote string) transport.AuthMethod {
+	if c.token == "" {
+		return nil
+	}
+	u, err := url.Parse(remote)
+	if err != nil || u.Scheme != "https" || !strings.EqualFold(u.Host, c.authHost) {
+		return nil
+	}
+	return &githttp.BasicAuth{Username: "x-access-token", Password: c.token}
+}
+
+// originAuth returns the credentials for the origin remote of repo
+func (c goGitCommand) originAuth(repo *git.Repository) transport.AuthMethod {
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil || len(remote.Config().URLs) == 0 {
+		return nil
+	}
+	return c.auth(remote.Config().URLs[0])
+}
+
+func (c goGitCommand) clone() error {
+	var branch, remote, path string
+	args := c.args[1:]
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--branch" && len(args) > 1:
+			branch = args[1]
+			args = args[2:]
+		case remote == "":
+			remote, args = args[0], args[1:]
+		case path == "":
+			path, args = args[0], args[1:]
+		default:
+			return fmt.Errorf("%w: git %s", ErrUnsupportedGitCommand, strings.Join(c.args, " "))
+		}
+	}
+	if remote == "" || path == "" {
+		return fmt.Errorf("%w: git %s", ErrUnsupportedGitCommand, strings.Join(c.args, " "))
+	}
+	if !filepath.IsAbs(path) && c.dir != "" {
+		path = filepath.Join(c.dir, path)
+	}
+
+	opts := &git.CloneOptions{URL: remote, Auth: c.auth(remote)}
+	if branch != "" {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(branch)
+	}
+	if _, err := git.PlainCloneContext(c.ctx, path, false, opts); err != nil {
+		// git leaves nothing behind when a clone fails
+		os.RemoveAll(path)
+		return err
+	}
+	return nil
+}
+
+func (c goGitCommand) fetch() error {
+	repo, err := c.open()
+	if err != nil {
+		return err
+	}
+	err = repo.FetchContext(c.ctx, &git.FetchOptions{Auth: c.originAuth(repo)})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return err
+}
+
+// pull fast-forwards the current branch from origin; go-git refuses merges.
+// A work tree with uncommitted changes is refused up front.
+func (c goGitCommand) pull() error {
+	repo, err := c.open()
+	if err != nil {
+		return err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	// go-git moves the branch before it checks the work tree, and would
+	// leave upstream's changes staged as a revert of themselves
+	status, err := worktree.Status()
+	if err != nil {
+		return err
+	}
+	for _, file := range status {
+		if file.Worktree != git.Untracked && (file.Staging != git.Unmodified || file.Worktree != git.Unmodified) {
+			return errors.New("cannot pull with uncommitted changes to tracked files")
+		}
+	}
+
+	err = worktree.PullContext(c.ctx, &git.PullOptions{
+		RemoteName:    git.DefaultRemoteName,
+		ReferenceName: head.Name(),
+		Auth:          c.originAuth(repo),
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return err
+}
+
+func (c goGitCommand) currentBranch() (string, error) {
+	repo, err := c.open()
+	if err != nil {
+		return "", err
+	}
+	// HEAD is read without resolving it, so a branch without commits is
+	// still reported, as git does
+	ref, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", err
+	}
+	if ref.Type() != plumbing.SymbolicReference || !ref.Target().IsBranch() {
+		return "\n", nil
+	}
+	return ref.Target().Short() + "\n", nil
+}
+
+func (c goGitCommand) head() (string, error) {
+	repo, err := c.open()
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	return head.Hash().String() + "\n", nil
+}
+
+// status lists modified tracked files in the porcelain format
+func (c goGitCommand) status() (string, error) {
+	repo, err := c.open()
+	if err != nil {
+		return "", err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, path := range slices.Sorted(maps.Keys(status)) {
+		file := status[path]
+		if file.Worktree == git.Untracked || (file.Staging == git.Unmodified && file.Worktree == git.Unmodified) {
+			continue
+		}
+		fmt.Fprintf(&b, "%c%c %s\n", file.Staging, file.Worktree, path)
+	}
+	return b.String(), nil
+}
+
+// branches lists local branches in branchFormat, working out where each
+// stands against its upstream as git does
+func (c goGitCommand) branches() (string, error) {
+	repo, err := c.open()
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return "", err
+	}
+	refs, err := repo.Branches()
+	if err != nil {
+		return "", err
+	}
+	var branches []*plumbing.Reference
+	refs.ForEach(func(ref *plumbing.Reference) error {
+		branches = append(branches, ref)
+		return nil
+	})
+	slices.SortFunc(branches, func(a, b *plumbing.Reference) int {
+		return strings.Compare(a.Name().String(), b.Name().String())
+	})
+
+	worktrees, err := c.worktrees(repo)
+	if err != nil {
+		return "", err
+	}
+	if head.Type() == plumbing.SymbolicReference {
+		if top, err := c.topLevel(); err == nil {
+			worktrees[head.Target()] = strings.TrimSpace(top)
+		}
+	}
+
+	var b strings.Builder
+	for _, ref := range branches {
+		current := " "
+		if head.Type() == plumbing.SymbolicReference && head.Target() == ref.Name() {
+			current = "*"
+		}
+		var upstream, track string
+		if branch, ok := cfg.Branches[ref.Name().Short()]; ok && branch.Remote != "" && branch.Merge != "" {
+			upstream = plumbing.NewRemoteReferenceName(branch.Remote, branch.Merge.Short()).String()
+			if branch.Remote == "." {
+				upstream = branch.Merge.String()
+			}
+			track = c.track(repo, ref.Hash(), plumbing.ReferenceName(upstream))
+		}
+		fmt.Fprintf(&b, "%s\t%s\t%s\t%s\t%s\t%s\n", current, ref.Name(), ref.Hash(), upstream, track, worktrees[ref.Name()])
+	}
+	return b.String(), nil
+}
+
+// worktrees maps the branches checked out in linked work trees to the work
+// tree paths, read from the administrative files git keeps for each of them
+// below .git/worktrees, which go-git does not manage itself
+func (c goGitCommand) worktrees(repo *git.Repository) (map[plumbing.ReferenceName]string, error) {
+	worktrees := make(map[plumbing.ReferenceName]string)
+	storage, ok := repo.Storer.(*filesystem.Storage)
+	if !ok {
+		return worktrees, nil
+	}
+	admin := filepath.Join(storage.Filesystem().Root(), "worktrees")
+	entries, err := os.ReadDir(admin)
+	if errors.Is(err, os.ErrNotExist) {
+		return worktrees, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		head, err := os.ReadFile(filepath.Join(admin, entry.Name(), "HEAD"))
+		if err != nil {
+			continue
+		}
+		target, ok := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: ")
+		if !ok {
+			continue
+		}
+		gitdir, err := os.ReadFile(filepath.Join(admin, entry.Name(), "gitdir"))
+		if err != nil {
+			continue
+		}
+		worktrees[plumbing.ReferenceName(target)] = filepath.Dir(strings.TrimSpace(string(gitdir)))
+	}
+	return worktrees, nil
+}
+
+// track compares a commit with an upstream ref like git's
+// %(upstream:trackshort); it is empty when the upstream is missing
+func (c goGitCommand) track(repo *git.Repository, local plumbing.Hash, upstream plumbing.ReferenceName) string {
+	ref, err := repo.Reference(upstream, true)
+	if err != nil {
+		return ""
+	}
+	if ref.Hash() == local {
+		return "="
+	}
+	localCommit, err := repo.CommitObject(local)
+	if err != nil {
+		return ""
+	}
+	upstreamCommit, err := repo.CommitObject(ref.Hash())
+	if err != nil {
+		return ""
+	}
+	if behind, err := localCommit.IsAncestor(upstreamCommit); err == nil && behind {
+		return "<"
+	}
+	if ahead, err := upstreamCommit.IsAncestor(localCommit); err == nil && ahead {
+		return ">"
+	}
+	return "<>"
+}
+
+// updateRef points ref at the commit newRev resolves to, provided ref is
+// still at old
+func (c goGitCommand) updateRef(ref, newRev, old string) error {
+	repo, err := c.open()
+	if err != nil {
+		return err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(newRev))
+	if err != nil {
+		return err
+	}
+	name := plumbing.ReferenceName(ref)
+	return repo.Storer.CheckAndSetReference(
+		plumbing.NewHashReference(name, *hash),
+		plumbing.NewHashReference(name, plumbing.NewHash(old)),
+	)
+}
+
+// topLevel returns the root of the work tree. go-git does not search parent
+// directories, so only a repository at dir itself is found.
+func (c goGitCommand) topLevel() (string, error) {
+	repo, err := c.open()
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return "", fmt.Errorf("%w: %s", errNotARepository, c.dir)
+	}
+	if err != nil {
+		return "", err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	top, err := filepath.Abs(worktree.Filesystem.Root())
+	if err != nil {
+		return "", err
+	}
+	return top + "\n", nil
+}
+
+func (c goGitCommand) originURL() (string, error) {
+	repo, err := c.open()
+	if err != nil {
+		return "", err
+	}
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return "", err
+	}
+	if len(remote.Config().URLs) == 0 {
+		return "", errors.New("origin remote has no URL")
+	}
+	return remote.Config().URLs[0] + "\n", nil
+}
+
+func (c goGitCommand) setOriginURL(remote string) error {
+	repo, err := c.open()
+	if err != nil {
+		return err
+	}
+	cfg, err := repo.Config()
+	if err != nil {
+		return err
+	}
+	origin, ok := cfg.Remotes[git.DefaultRemoteName]
+	if !ok {
+		return git.ErrRemoteNotFound
+	}
+	origin.URLs = []string{remote}
+	return repo.SetConfig(cfg)
+}
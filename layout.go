@@ -0,0 +1,97 @@
+package gitgrab
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Templates behind the layout names; CloneConfig.Layout takes either
+const (
+	FlatTemplate  = "{name}"
+	OwnerTemplate = "{owner}/{name}"
+)
+
+// NoTopic is the {topic} of a repository without topics
+const NoTopic = "no-topic"
+
+var layoutPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// layoutPlaceholders are the placeholders a layout template may use
+var layoutPlaceholders = map[string]bool{
+	"{host}":       true,
+	"{owner}":      true,
+	"{name}":       true,
+	"{topic}":      true,
+	"{visibility}": true,
+}
+
+// ValidateLayout checks a layout name or template. A template is a relative
+// slash-separated path of literal directory names and the placeholders
+// {host}, {owner}, {name}, {topic} and {visibility}, and must contain {name}
+// so that every repository gets a directory of its own.
+func ValidateLayout(layout string) error {
+	switch layout {
+	case "", LayoutAuto, LayoutFlat, LayoutOwner:
+		return nil
+	}
+	if !strings.Contains(layout, "{") {
+		return fmt.Errorf("invalid layout: %s", layout)
+	}
+	if !strings.Contains(layout, "{name}") {
+		return fmt.Errorf("invalid layout template %s: must contain {name}", layout)
+	}
+	for _, placeholder := range layoutPlaceholder.FindAllString(layout, -1) {
+		if !layoutPlaceholders[placeholder] {
+			return fmt.Errorf("invalid layout template %s: unknown placeholder %s", layout, placeholder)
+		}
+	}
+	for _, element := range strings.Split(layout, "/") {
+		if element == "" || element == "." || element == ".." {
+			return fmt.Errorf("invalid layout template %s: must be a relative path without empty, . or .. elements", layout)
+		}
+	}
+	if strings.ContainsAny(layoutPlaceholder.ReplaceAllString(layout, ""), "{}\\") {
+		return fmt.Errorf("invalid layout template %s: stray brace or backslash", layout)
+	}
+	return nil
+}
+
+// LayoutTemplate returns the template for a layout name, resolving
+// LayoutAuto by the number of owners synced. Templates are returned as is.
+func LayoutTemplate(layout string, owners int) string {
+	switch layout {
+	case "", LayoutFlat:
+		return FlatTemplate
+	case LayoutOwner:
+		return OwnerTemplate
+	case LayoutAuto:
+		if owners > 1 {
+			return OwnerTemplate
+		}
+		return FlatTemplate
+	}
+	return layout
+}
+
+// layoutPath expands the layout template of a clone configuration into the
+// slash-separated path of the repository below the target directory
+func (c CloneConfig) layoutPath() string {
+	owner := c.Repository.Owner.Login
+	if c.Organization != "" {
+		owner = c.Organization.String()
+	}
+	topic := NoTopic
+	if len(c.Repository.Topics) > 0 {
+		topic = c.Repository.Topics[0]
+	}
+
+	return path.Clean(strings.NewReplacer(
+		"{host}", c.Host.String(),
+		"{owner}", owner,
+		"{name}", c.Repository.Name.String(),
+		"{topic}", topic,
+		"{visibility}", c.Repository.EffectiveVisibility().String(),
+	).Replace(LayoutTemplate(c.Layout, 1)))
+}
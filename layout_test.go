@@ -0,0 +1,99 @@
+package gitgrab
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateLayout(t *testing.T) {
+	for layout, valid := range map[string]bool{
+		"":                        true,
+		LayoutAuto:                true,
+		LayoutOwner:               true,
+		"{host}/{owner}/{name}":   true,
+		"{owner}/{topic}/{name}":  true,
+		"src/{visibility}-{name}": true,
+		"nested":                  false,
+		"{owner}":                 false,
+		"{org}/{name}":            false,
+		"/{name}":                 false,
+		"{owner}//{name}":         false,
+		"../{name}":               false,
+		"{owner}}/{name}":         false,
+	} {
+		if err := ValidateLayout(layout); (err == nil) != valid {
+			t.Errorf("ValidateLayout(%q) = %v, expected valid %v", layout, err, valid)
+		}
+	}
+}
+
+func TestCloneConfig_RepoPath_Layout(t *testing.T) {
+	repo := Repository{
+		Name:    "api",
+		Owner:   RepositoryOwner{Login: "acme"},
+		Private: true,
+		Topics:  []string{"backend", "go"},
+	}
+
+	tests := []struct {
+		layout   string
+		host     GitHost
+		repo     Repository
+		expected string
+	}{
+		{"", "", repo, "api"},
+		{LayoutFlat, "", repo, "api"},
+		{LayoutOwner, "", repo, "acme/api"},
+		{"{host}/{owner}/{name}", "", repo, "github.com/acme/api"},
+		{"{host}/{owner}/{name}", "git.example.com", repo, "git.example.com/acme/api"},
+		{"{owner}/{topic}/{name}", "", repo, "acme/backend/api"},
+		{"{owner}/{topic}/{name}", "", Repository{Name: "api", Owner: repo.Owner}, "acme/no-topic/api"},
+		{"{visibility}/{name}", "", repo, "private/api"},
+	}
+
+	for _, tt := range tests {
+		config := CloneConfig{Repository: tt.repo, TargetDir: "/src", Layout: tt.layout, Host: tt.host}
+		if path := config.RepoPath(); path != filepath.Join("/src", filepath.FromSlash(tt.expected)) {
+			t.Errorf("Layout %q: expected %s, got %s", tt.layout, tt.expected, path)
+		}
+	}
+}
+
+func TestSyncer_RefusesRepositoriesSharingADirectory(t *testing.T) {
+	configs := []CloneConfig{
+		{Repository: Repository{Name: "api", FullName: "acme/api"}},
+		{Repository: Repository{Name: "web", FullName: "acme/web"}},
+		{Repository: Repository{Name: "API", FullName: "other/API"}},
+	}
+	for i := range configs {
+		configs[i].TargetDir = "/tmp/unused"
+		configs[i].Layout = "{visibility}/{name}"
+	}
+
+	var synced []RepositoryName
+	syncer := NewSyncer(2, ReporterFunc(func(Event) {}))
+	syncer.sync = func(ctx context.Context, config CloneConfig) SyncResult {
+		synced = append(synced, config.Repository.Name)
+		return SyncResult{Action: ActionClone}
+	}
+	summary := syncer.Sync(configs)
+
+	if !reflect.DeepEqual(synced, []RepositoryName{"web"}) {
+		t.Errorf("Expected only web to be synced, got %v", synced)
+	}
+	if summary.Failed != 2 || summary.Succeeded != 1 {
+		t.Errorf("Expected 2 failures and 1 success, got %+v", summary)
+	}
+	for _, i := range []int{0, 2} {
+		if err := summary.Results[i].Err; !errors.Is(err, ErrPathConflict) {
+			t.Errorf("Expected %s to fail with a path conflict, got %v", configs[i].Repository.FullName, err)
+		}
+	}
+	if err := summary.Results[0].Err; err == nil || !strings.Contains(err.Error(), "other/API") {
+		t.Errorf("Expected the error to name the other repository, got %v", err)
+	}
+}
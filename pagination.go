@@ -0,0 +1,172 @@
+package gitgrab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// perPage is the page size requested from list endpoints (the API maximum)
+const perPage = 100
+
+// parseLinkHeader extracts the URLs from an RFC 8288 Link header, keyed by
+// their rel value (next, last, prev, first)
+func parseLinkHeader(header string) map[string]string {
+	links := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		segments := strings.Split(part, ";")
+		if len(segments) < 2 {
+			continue
+		}
+
+		target := strings.TrimSpace(segments[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		target = target[1 : len(target)-1]
+
+		for _, param := range segments[1:] {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || strings.TrimSpace(key) != "rel" {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+				links[rel] = target
+			}
+		}
+	}
+	return links
+}
+
+// pageNumber returns the value of the page query parameter of a page URL
+func pageNumber(rawURL string) (int, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(u.Query().Get("page"))
+}
+
+// withPage returns rawURL with its page query parameter set to page
+func withPage(rawURL string, page int) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// getJSON decodes the response to a GET request into v and returns the
+// response headers
+func getJSON(ctx context.Context, gc *GitHubClient, url string, v any) (http.Header, error) {
+	resp, err := gc.makeRequest(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed: %s - %s", resp.Status, string(body))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return resp.Header, nil
+}
+
+// fetchPage decodes a single page of a list endpoint into items and returns
+// the links advertised for the remaining pages
+func fetchPage[T any](ctx context.Context, gc *GitHubClient, url string) ([]T, map[string]string, error) {
+	var items []T
+	header, err := getJSON(ctx, gc, url, &items)
+	if err != nil {
+		return nil, nil, err
+	}
+	return items, parseLinkHeader(header.Get("Link")), nil
+}
+
+// fetchPages retrieves every page of a list endpoint by following the
+// rel="next" links GitHub returns. When the client allows concurrent page
+// fetches and the first response advertises rel="last", the remaining pages
+// are requested in parallel instead.
+func fetchPages[T any](ctx context.Context, gc *GitHubClient, firstURL string) ([]T, error) {
+	items, links, err := fetchPage[T](ctx, gc, firstURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if last, ok := links["last"]; ok && gc.pageConcurrency > 1 {
+		if rest, ok, err := fetchRemainingPages[T](ctx, gc, last); ok {
+			if err != nil {
+				return nil, err
+			}
+			return append(items, rest...), nil
+		}
+	}
+
+	for next := links["next"]; next != ""; next = links["next"] {
+		var page []T
+		page, links, err = fetchPage[T](ctx, gc, next)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+	}
+
+	return items, nil
+}
+
+// fetchRemainingPages fetches pages 2 through the page referenced by lastURL
+// concurrently, preserving page order. The boolean result is false when the
+// last page number cannot be determined, in which case the caller should fall
+// back to following rel="next" links.
+func fetchRemainingPages[T any](ctx context.Context, gc *GitHubClient, lastURL string) ([]T, bool, error) {
+	lastPage, err := pageNumber(lastURL)
+	if err != nil || lastPage < 2 {
+		return nil, false, nil
+	}
+
+	urls := make([]string, 0, lastPage-1)
+	for page := 2; page <= lastPage; page++ {
+		pageURL, err := withPage(lastURL, page)
+		if err != nil {
+			return nil, false, nil
+		}
+		urls = append(urls, pageURL)
+	}
+
+	pages := make([][]T, len(urls))
+	errs := make([]error, len(urls))
+	sem := make(chan struct{}, gc.pageConcurrency)
+	var wg sync.WaitGroup
+	for i, pageURL := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, pageURL string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			pages[i], _, errs[i] = fetchPage[T](ctx, gc, pageURL)
+		}(i, pageURL)
+	}
+	wg.Wait()
+
+	var items []T
+	for i := range pages {
+		if errs[i] != nil {
+			return nil, true, errs[i]
+		}
+		items = append(items, pages[i]...)
+	}
+	return items, true, nil
+}
@@ -0,0 +1,127 @@
+package gitgrab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseLinkHeader(t *testing.T) {
+	header := `<https://api.github.com/organizations/1/repos?page=2&per_page=100>; rel="next", ` +
+		`<https://api.github.com/organizations/1/repos?page=5&per_page=100>; rel="last"`
+
+	links := parseLinkHeader(header)
+
+	if links["next"] != "https://api.github.com/organizations/1/repos?page=2&per_page=100" {
+		t.Errorf("Unexpected next link: %s", links["next"])
+	}
+	if links["last"] != "https://api.github.com/organizations/1/repos?page=5&per_page=100" {
+		t.Errorf("Unexpected last link: %s", links["last"])
+	}
+	if _, ok := links["prev"]; ok {
+		t.Error("Expected no prev link")
+	}
+}
+
+func TestParseLinkHeader_Empty(t *testing.T) {
+	if links := parseLinkHeader(""); len(links) != 0 {
+		t.Errorf("Expected no links, got %v", links)
+	}
+}
+
+// newPagedServer serves pages 1..pages of a repository listing, one repo per
+// page, advertising next and last links like the GitHub API does
+func newPagedServer(t *testing.T, pages int, requests *int32) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+
+		page := 1
+		if p := r.URL.Query().Get("page"); p != "" {
+			page, _ = strconv.Atoi(p)
+		}
+		if page > pages {
+			t.Errorf("Requested page %d beyond last page %d", page, pages)
+		}
+
+		if page < pages {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos?page=%d&per_page=1>; rel="next", <%s/repos?page=%d&per_page=1>; rel="last"`,
+				server.URL, page+1, server.URL, pages))
+		}
+		json.NewEncoder(w).Encode([]Repository{{Name: RepositoryName(fmt.Sprintf("repo%d", page))}})
+	}))
+	return server
+}
+
+func TestFetchPages_FollowsNextLinks(t *testing.T) {
+	var requests int32
+	server := newPagedServer(t, 3, &requests)
+	defer server.Close()
+
+	client := NewGitHubClient(GitHubToken("test-token"))
+	repos, err := fetchPages[Repository](context.Background(), client, server.URL+"/repos?per_page=1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(repos) != 3 {
+		t.Fatalf("Expected 3 repositories, got %d", len(repos))
+	}
+	if requests != 3 {
+		t.Errorf("Expected exactly 3 requests, got %d", requests)
+	}
+	for i, repo := range repos {
+		if expected := fmt.Sprintf("repo%d", i+1); repo.Name.String() != expected {
+			t.Errorf("Expected repo %d to be %s, got %s", i, expected, repo.Name)
+		}
+	}
+}
+
+func TestFetchPages_ConcurrentPreservesOrder(t *testing.T) {
+	var requests int32
+	server := newPagedServer(t, 6, &requests)
+	defer server.Close()
+
+	client := NewGitHubClient(GitHubToken("test-token"), WithPageConcurrency(4))
+	repos, err := fetchPages[Repository](context.Background(), client, server.URL+"/repos?per_page=1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(repos) != 6 {
+		t.Fatalf("Expected 6 repositories, got %d", len(repos))
+	}
+	if requests != 6 {
+		t.Errorf("Expected exactly 6 requests, got %d", requests)
+	}
+	for i, repo := range repos {
+		if expected := fmt.Sprintf("repo%d", i+1); repo.Name.String() != expected {
+			t.Errorf("Expected repo %d to be %s, got %s", i, expected, repo.Name)
+		}
+	}
+}
+
+func TestFetchPages_ErrorOnLaterPage(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Link", fmt.Sprintf(`<%s/repos?page=2>; rel="next", <%s/repos?page=2>; rel="last"`, server.URL, server.URL))
+		json.NewEncoder(w).Encode([]Repository{{Name: "repo1"}})
+	}))
+	defer server.Close()
+
+	for _, concurrency := range []int{1, 4} {
+		client := NewGitHubClient(GitHubToken("test-token"), WithPageConcurrency(concurrency), WithMaxRetries(0))
+		if _, err := fetchPages[Repository](context.Background(), client, server.URL+"/repos"); err == nil {
+			t.Errorf("Expected error with page concurrency %d, got none", concurrency)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package gitgrab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Action is what a sync does with a single repository
+type Action string
+
+const (
+	ActionClone Action = "clone"
+	ActionPull  Action = "pull"
+	ActionFetch Action = "fetch"
+	ActionSkip  Action = "skip"
+	// ActionReclone replaces a directory holding a broken or empty
+	// repository with a fresh clone
+	ActionReclone Action = "reclone"
+)
+
+// ErrNotGitRepository is returned when a repository's directory exists but
+// holds something other than a git repository, which gitgrab will not
+// overwrite
+var ErrNotGitRepository = errors.New("directory exists but is not a git repository")
+
+// PlanEntry describes what syncing a repository would do, without doing it
+type PlanEntry struct {
+	Repository    RepositoryName `json:"repository"`
+	Owner         string         `json:"owner,omitempty"`
+	Path          string         `json:"path"`
+	Action        Action         `json:"action"`
+	CurrentBranch string         `json:"current_branch,omitempty"`
+	TrackedBranch BranchName     `json:"tracked_branch,omitempty"`
+	Reason        string         `json:"reason"`
+	// Warning explains why an update falls back to a plain fetch
+	Warning string `json:"warning,omitempty"`
+	// Origin is the current origin remote when it is rewritten before
+	// updating, because it points at another repository or uses another
+	// clone method
+	Origin string `json:"origin,omitempty"`
+
+	// MovedFrom is where the repository was synced before it was renamed,
+	// transferred or moved by a layout change; it is moved to Path
+	MovedFrom string `json:"moved_from,omitempty"`
+	// RenamedFrom is the previous default branch when the default branch
+	// was renamed upstream and the clone is migrated before updating
+	RenamedFrom string `json:"renamed_from,omitempty"`
+
+	// err is the error a sync reports for a skipped repository
+	err error
+}
+
+// RepoPath returns the directory a repository is cloned into
+func (c CloneConfig) RepoPath() string {
+	return filepath.Join(c.TargetDir, filepath.FromSlash(c.layoutPath()))
+}
+
+// PlanRepo inspects the local state of a repository and decides whether a
+// sync would clone it, pull it or only fetch it. CloneRepo acts on the same
+// decision, so a plan always matches what a sync does.
+func PlanRepo(config CloneConfig) PlanEntry {
+	return planRepo(context.Background(), config)
+}
+
+// inspectingGit runs git without optional locks, so planning never writes to
+// a repository, as git status otherwise may when it refreshes the index
+type inspectingGit struct {
+	GitRunner
+}
+
+func (g inspectingGit) Run(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	return g.GitRunner.Run(ctx, dir, append(env, "GIT_OPTIONAL_LOCKS=0"), args...)
+}
+
+func planRepo(ctx context.Context, config CloneConfig) PlanEntry {
+	git := inspectingGit{config.git()}
+	entry := PlanEntry{
+		Repository:    config.Repository.Name,
+		Owner:         config.Repository.Owner.Login,
+		Path:          config.RepoPath(),
+		TrackedBranch: config.trackedBranch(),
+	}
+
+	// A repository found elsewhere by its ID is inspected where it is,
+	// and moved into place before it is updated
+	path := entry.Path
+	if _, err := os.Stat(path); err != nil && config.Previous != nil && config.Previous.Path != path {
+		if _, err := os.Stat(config.Previous.Path); err == nil {
+			entry.MovedFrom = config.Previous.Path
+			path = entry.MovedFrom
+		}
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		entry.Action = ActionClone
+		entry.Reason = "not cloned yet"
+		return entry
+	}
+
+	reason, err := checkRepository(ctx, git, path)
+	if (reason != "" || err != nil) && entry.MovedFrom != "" {
+		// Whatever is left where the repository used to be stays there
+		entry.MovedFrom = ""
+		entry.Action = ActionClone
+		entry.Reason = "not cloned yet"
+		return entry
+	}
+	if reason != "" {
+		entry.Action = ActionReclone
+		entry.Reason = reason
+		return entry
+	}
+	if err != nil {
+		entry.Action = ActionSkip
+		entry.Reason = err.Error()
+		entry.err = err
+		return entry
+	}
+
+	// Archived repositories don't change upstream, so one sync after they
+	// were archived is enough; a move still happens
+	if archivedBefore(config) && entry.MovedFrom == "" {
+		entry.Action = ActionSkip
+		entry.Reason = "archived upstream"
+		return entry
+	}
+
+	// An unreadable origin is left to the update to report. Credentials
+	// embedded by older versions are scrubbed before the origin is checked,
+	// so they never show up in plans or messages.
+	if origin, err := git.Run(ctx, path, nil, "remote", "get-url", "origin"); err == nil {
+		origin, _ = stripCredentials(strings.TrimSpace(origin))
+		if expected := cloneURL(config); origin != "" && !sameRemote(origin, expected) {
+			mismatch := fmt.Sprintf("origin is %s, expected %s", origin, expected)
+			switch {
+			case config.RemoteMismatch == RemoteMismatchRewrite, renamedFrom(config, origin):
+				entry.Origin = origin
+			case config.RemoteMismatch == RemoteMismatchFail:
+				entry.Action = ActionSkip
+				entry.Reason = mismatch
+				entry.err = fmt.Errorf("%w: %s", ErrRemoteMismatch, mismatch)
+				return entry
+			default:
+				entry.Action = ActionSkip
+				entry.Reason = mismatch
+				return entry
+			}
+		} else if config.FixRemotes && origin != expected {
+			entry.Origin = origin
+		}
+	}
+
+	if entry.TrackedBranch == "" {
+		entry.Action = ActionFetch
+		entry.Reason = "no default branch information"
+		entry.Warning = fmt.Sprintf("No default branch information for %s", config.Repository.Name)
+		return entry
+	}
+
+	currentBranch, err := getCurrentBranch(ctx, git, path)
+	if err != nil {
+		entry.Action = ActionFetch
+		entry.Reason = "current branch unknown"
+		entry.Warning = fmt.Sprintf("Could not determine current branch for %s: %v", config.Repository.Name, err)
+		return entry
+	}
+	entry.CurrentBranch = currentBranch
+
+	// origin/HEAD still names the default branch from when the repository
+	// was cloned; an override means the default branch isn't tracked
+	onTracked := BranchName(currentBranch) == entry.TrackedBranch
+	if config.Branch == "" {
+		if previous := remoteDefaultBranch(ctx, git, path); previous != "" && BranchName(previous) != entry.TrackedBranch {
+			entry.RenamedFrom = previous
+			// The checked out branch is renamed along with the default
+			if currentBranch == previous && !branchExists(ctx, git, path, entry.TrackedBranch.String()) {
+				onTracked = true
+			}
+		}
+	}
+
+	if onTracked {
+		entry.Action = ActionPull
+		entry.Reason = fmt.Sprintf("on default branch %s", currentBranch)
+		if config.UpdateStrategy == UpdateSkipIfDirty {
+			dirty, err := hasLocalChanges(ctx, git, path)
+			if err != nil {
+				entry.Action = ActionSkip
+				entry.Reason = fmt.Sprintf("could not check for local changes: %v", err)
+			} else if dirty {
+				entry.Action = ActionSkip
+				entry.Reason = fmt.Sprintf("uncommitted changes on %s", currentBranch)
+			}
+		}
+	} else {
+		entry.Action = ActionFetch
+		entry.Reason = fmt.Sprintf("on branch %s, not %s", currentBranch, entry.TrackedBranch)
+	}
+	return entry
+}
+
+// renamedFrom reports whether origin points at the repository under the name
+// it had when it was last synced, before being renamed or transferred
+func renamedFrom(config CloneConfig, origin string) bool {
+	previous := config.Previous
+	if previous == nil || previous.FullName == "" || strings.EqualFold(previous.FullName, config.Repository.FullName) {
+		return false
+	}
+	return strings.HasSuffix(normalizeRemote(origin), "/"+strings.ToLower(previous.FullName))
+}
+
+// errNotARepository is returned by a GitRunner when git finds no repository
+// at all, as opposed to failing for other reasons
+var errNotARepository = errors.New("not a git repository")
+
+// notARepository reports whether a git error means the directory holds no
+// repository git can read
+func notARepository(err error) bool {
+	return errors.Is(err, errNotARepository) || strings.Contains(err.Error(), "not a git repository")
+}
+
+// checkRepository inspects what path holds. A non-empty reason means the
+// directory is provably not a usable repository and can be recloned: it is
+// empty, its .git lacks HEAD or objects, or git does not recognise it. Any
+// other problem, such as a directory without .git, or git failing because of
+// ownership, permissions or a timeout, is returned as an error and the
+// directory is left alone.
+func checkRepository(ctx context.Context, git GitRunner, path string) (string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "empty directory", nil
+	}
+	gitDir := filepath.Join(path, ".git")
+	info, err := os.Lstat(gitDir)
+	if err != nil {
+		return "", ErrNotGitRepository
+	}
+	// A .git file points elsewhere, as for worktrees and submodules
+	if info.IsDir() {
+		for _, name := range []string{"HEAD", "objects"} {
+			if _, err := os.Lstat(filepath.Join(gitDir, name)); errors.Is(err, os.ErrNotExist) {
+				return "broken repository", nil
+			}
+		}
+	}
+
+	output, err := git.Run(ctx, path, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		if notARepository(err) {
+			return "broken repository", nil
+		}
+		return "", fmt.Errorf("cannot inspect repository: %w", err)
+	}
+	top, err := os.Stat(strings.TrimSpace(output))
+	if err != nil {
+		return "", fmt.Errorf("cannot inspect repository: %w", err)
+	}
+	dir, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	// git searches parent directories for a repository, so finding another
+	// repository's work tree means git does not recognise this .git
+	if !os.SameFile(top, dir) {
+		return "broken repository", nil
+	}
+	return "", nil
+}
+
+// SkipEntry describes a repository that is deliberately left alone
+func SkipEntry(config CloneConfig, reason string) PlanEntry {
+	return PlanEntry{
+		Repository: config.Repository.Name,
+		Owner:      config.Repository.Owner.Login,
+		Path:       config.RepoPath(),
+		Action:     ActionSkip,
+		Reason:     reason,
+	}
+}
+
+// Plan computes a plan entry for every config, in order
+func Plan(configs []CloneConfig) []PlanEntry {
+	entries := make([]PlanEntry, 0, len(configs))
+	for _, config := range configs {
+		entries = append(entries, PlanRepo(config))
+	}
+	return entries
+}
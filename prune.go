@@ -0,0 +1,210 @@
+package gitgrab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Policies for local clones of repositories no longer listed upstream,
+// accepted for PruneConfig.Policy
+const (
+	// PruneReport lists orphaned clones and leaves them in place
+	PruneReport = "report"
+	// PruneAttic moves orphaned clones below AtticDir
+	PruneAttic = "attic"
+	// PruneDelete removes orphaned clones that hold no unpushed work
+	PruneDelete = "delete"
+)
+
+// AtticDir is the directory below the target directory where PruneAttic
+// moves orphaned clones, keeping their path relative to the target directory
+const AtticDir = "_attic"
+
+// ValidatePrune checks a prune policy name
+func ValidatePrune(policy string) error {
+	switch policy {
+	case "", PruneReport, PruneAttic, PruneDelete:
+		return nil
+	}
+	return fmt.Errorf("invalid prune policy: %s", policy)
+}
+
+// PruneConfig groups the parameters for pruning a target directory
+type PruneConfig struct {
+	TargetDir string
+	// Listed holds the path of every repository listed upstream, including
+	// those excluded by filters or configuration
+	Listed []string
+	// Owners are the accounts whose repositories were listed in full. Only
+	// clones whose origin belongs to one of them can be orphaned, so
+	// unrelated checkouts and partial listings such as teams are safe.
+	Owners []string
+	Policy string
+	// Reporter receives progress events; defaults to a ConsoleReporter on
+	// os.Stdout when nil
+	Reporter Reporter
+	// Git runs git commands; defaults to ExecGitRunner when nil
+	Git GitRunner
+}
+
+func (c PruneConfig) git() GitRunner {
+	if c.Git == nil {
+		return ExecGitRunner{}
+	}
+	return c.Git
+}
+
+func (c PruneConfig) reporter() Reporter {
+	if c.Reporter == nil {
+		return NewConsoleReporter(os.Stdout)
+	}
+	return c.Reporter
+}
+
+// Orphan is a local clone of a repository no longer listed upstream
+type Orphan struct {
+	Path   string
+	Origin string
+}
+
+// FindOrphans walks the target directory for clones that are not at the
+// path of any listed repository. Hidden directories, such as the state and
+// half-finished clones, and AtticDir are not searched.
+func FindOrphans(ctx context.Context, config PruneConfig) ([]Orphan, error) {
+	listed := make(map[string]bool)
+	for _, path := range config.Listed {
+		listed[filepath.Clean(path)] = true
+	}
+	owners := make(map[string]bool)
+	for _, owner := range config.Owners {
+		owners[strings.ToLower(owner)] = true
+	}
+
+	root := filepath.Clean(config.TargetDir)
+	var orphans []Orphan
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || path == root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || path == filepath.Join(root, AtticDir) || listed[path] {
+			return filepath.SkipDir
+		}
+		// Directories that are not clones, such as owner directories, may
+		// hold clones further down
+		if _, err := os.Lstat(filepath.Join(path, ".git")); err != nil {
+			return nil
+		}
+
+		if origin, err := config.git().Run(ctx, path, nil, "remote", "get-url", "origin"); err == nil {
+			origin = strings.TrimSpace(origin)
+			if owners[remoteOwner(origin)] {
+				orphans = append(orphans, Orphan{Path: path, Origin: origin})
+			}
+		}
+		return filepath.SkipDir
+	})
+	return orphans, err
+}
+
+// remoteOwner returns the owner in a remote URL, the path element before
+// the repository name
+func remoteOwner(remote string) string {
+	parts := strings.Split(filepath.ToSlash(normalizeRemote(remote)), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.ToLower(parts[len(parts)-2])
+}
+
+// Prune applies the prune policy to orphaned clones found by FindOrphans. A
+// clone that cannot be pruned is reported and left in place; the error
+// returned is the first of those failures.
+func Prune(ctx context.Context, config PruneConfig, orphans []Orphan) error {
+	var firstErr error
+	for _, orphan := range orphans {
+		var err error
+		e := Event{Path: orphan.Path, Message: orphan.Origin}
+		switch config.Policy {
+		case PruneAttic:
+			e.Kind = EventPruned
+			e.Message, err = moveToAttic(config, orphan.Path)
+		case PruneDelete:
+			e.Kind = EventPruned
+			e.Message = ""
+			err = deleteOrphan(ctx, config, orphan.Path)
+		default:
+			e.Kind = EventOrphaned
+		}
+		if err != nil {
+			e.Kind = EventWarning
+			e.Message = fmt.Sprintf("Keeping orphaned clone %s: %v", orphan.Path, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		config.reporter().Report(e)
+	}
+	return firstErr
+}
+
+// moveToAttic moves an orphaned clone below AtticDir and returns its new path
+func moveToAttic(config PruneConfig, path string) (string, error) {
+	rel, err := filepath.Rel(config.TargetDir, path)
+	if err != nil {
+		return "", err
+	}
+	dest := filepath.Join(config.TargetDir, AtticDir, rel)
+	if _, err := os.Stat(dest); err == nil {
+		return "", fmt.Errorf("%s already exists", dest)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+	return dest, os.Rename(path, dest)
+}
+
+// deleteOrphan removes an orphaned clone unless it holds work that exists
+// nowhere else
+func deleteOrphan(ctx context.Context, config PruneConfig, path string) error {
+	work, err := unpushedWork(ctx, config.git(), path)
+	if err != nil {
+		return fmt.Errorf("could not check for unpushed work: %w", err)
+	}
+	if work != "" {
+		return errors.New(work)
+	}
+	return os.RemoveAll(path)
+}
+
+// unpushedWork describes the work in a clone that no remote has: changes
+// not committed, including untracked and ignored files such as local
+// configuration, commits on local branches only, and stashes. It returns an empty string for a clone that is safe to
+// delete.
+func unpushedWork(ctx context.Context, git GitRunner, repoPath string) (string, error) {
+	checks := []struct {
+		args []string
+		work string
+	}{
+		{[]string{"status", "--porcelain", "--ignored"}, "uncommitted changes or ignored files"},
+		{[]string{"rev-list", "--branches", "--not", "--remotes"}, "commits not pushed to any remote"},
+		{[]string{"stash", "list"}, "stashed changes"},
+	}
+	for _, check := range checks {
+		output, err := git.Run(ctx, repoPath, nil, check.args...)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(output) != "" {
+			return check.work, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,97 @@
+package gitgrab
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// cloneOrphans clones upstream repositories, given as owner/name, into a
+// target directory and returns the target directory
+func cloneOrphans(t *testing.T, names ...string) string {
+	t.Helper()
+	upstream := t.TempDir()
+	targetDir := t.TempDir()
+	for _, name := range names {
+		source := filepath.Join(upstream, name)
+		initRepoOnBranch(t, source, "main")
+		if err := exec.Command("git", "clone", "--quiet", source, filepath.Join(targetDir, filepath.Base(name))).Run(); err != nil {
+			t.Fatalf("Failed to clone %s: %v", name, err)
+		}
+	}
+	return targetDir
+}
+
+func TestFindOrphans(t *testing.T) {
+	targetDir := cloneOrphans(t, "acme/api", "acme/gone", "widgets/other")
+	os.MkdirAll(filepath.Join(targetDir, AtticDir, "old", ".git"), 0755)
+	os.MkdirAll(filepath.Join(targetDir, ".gone.gitgrab-clone", ".git"), 0755)
+
+	orphans, err := FindOrphans(context.Background(), PruneConfig{
+		TargetDir: targetDir,
+		Listed:    []string{filepath.Join(targetDir, "api")},
+		Owners:    []string{"Acme"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Clones of other owners, the attic and half-finished clones are left out
+	if len(orphans) != 1 || orphans[0].Path != filepath.Join(targetDir, "gone") {
+		t.Errorf("Expected only gone to be orphaned, got %+v", orphans)
+	}
+}
+
+func TestPrune_Attic(t *testing.T) {
+	targetDir := cloneOrphans(t, "acme/gone")
+	config := PruneConfig{TargetDir: targetDir, Owners: []string{"acme"}, Policy: PruneAttic, Reporter: ReporterFunc(func(Event) {})}
+
+	orphans, _ := FindOrphans(context.Background(), config)
+	if err := Prune(context.Background(), config, orphans); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, AtticDir, "gone", ".git")); err != nil {
+		t.Errorf("Expected gone to be moved to the attic: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "gone")); !os.IsNotExist(err) {
+		t.Errorf("Expected gone to have left the target directory, got %v", err)
+	}
+}
+
+func TestPrune_DeleteKeepsUnpushedWork(t *testing.T) {
+	targetDir := cloneOrphans(t, "acme/clean", "acme/dirty", "acme/ignored", "acme/ahead")
+	os.WriteFile(filepath.Join(targetDir, "dirty", "notes.txt"), []byte("draft"), 0644)
+	os.WriteFile(filepath.Join(targetDir, "ignored", ".git", "info", "exclude"), []byte(".env\n"), 0644)
+	os.WriteFile(filepath.Join(targetDir, "ignored", ".env"), []byte("SECRET=1\n"), 0644)
+	exec.Command("git", "-C", filepath.Join(targetDir, "ahead"), "-c", "user.email=test@example.com", "-c", "user.name=Test User",
+		"commit", "--allow-empty", "-m", "Local work").Run()
+
+	var kept []string
+	config := PruneConfig{
+		TargetDir: targetDir,
+		Owners:    []string{"acme"},
+		Policy:    PruneDelete,
+		Reporter: ReporterFunc(func(e Event) {
+			if e.Kind == EventWarning {
+				kept = append(kept, filepath.Base(e.Path))
+			}
+		}),
+	}
+
+	orphans, _ := FindOrphans(context.Background(), config)
+	if err := Prune(context.Background(), config, orphans); err == nil {
+		t.Error("Expected an error for clones that could not be deleted")
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "clean")); !os.IsNotExist(err) {
+		t.Errorf("Expected clean to be deleted, got %v", err)
+	}
+	for _, name := range []string{"dirty", "ignored", "ahead"} {
+		if _, err := os.Stat(filepath.Join(targetDir, name)); err != nil {
+			t.Errorf("Expected %s to be kept: %v", name, err)
+		}
+	}
+	if len(kept) != 3 {
+		t.Errorf("Expected warnings for the kept clones, got %v", kept)
+	}
+}
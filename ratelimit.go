@@ -0,0 +1,257 @@
+package gitgrab
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxRateLimitWait is the longest a client will wait in total for
+	// a single request before giving up with a RateLimitError. It covers a
+	// full primary rate limit window.
+	DefaultMaxRateLimitWait = time.Hour
+
+	// DefaultMaxRetries is how many times a request is retried after rate
+	// limit or server error responses
+	DefaultMaxRetries = 6
+
+	initialBackoff = time.Second
+	maxBackoff     = 2 * time.Minute
+
+	// secondaryRateLimitBackoff is the minimum pause GitHub recommends after a
+	// secondary rate limit response without a Retry-After header
+	secondaryRateLimitBackoff = time.Minute
+)
+
+// RateLimit is the request budget reported in the X-RateLimit-* headers
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// IsKnown reports whether the API has reported a budget yet
+func (r RateLimit) IsKnown() bool {
+	return r.Limit > 0
+}
+
+func (r RateLimit) String() string {
+	if !r.IsKnown() {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d/%d remaining, resets at %s", r.Remaining, r.Limit, r.Reset.Format(time.RFC3339))
+}
+
+// RateLimitError is returned when the API keeps rate limiting a request and
+// waiting any longer would exceed the client's wait ceiling
+type RateLimitError struct {
+	RateLimit RateLimit
+	// Wait is how long the API asked the client to wait before retrying
+	Wait time.Duration
+	// Secondary is true for secondary (abuse) rate limits, which are not
+	// reflected in the remaining budget
+	Secondary bool
+}
+
+func (e *RateLimitError) Error() string {
+	kind := "rate limit"
+	if e.Secondary {
+		kind = "secondary rate limit"
+	}
+	return fmt.Sprintf("GitHub API %s exceeded, retry after %s", kind, e.Wait.Round(time.Second))
+}
+
+// RetryNotice describes a pause before a request is retried
+type RetryNotice struct {
+	Attempt     int
+	Wait        time.Duration
+	StatusCode  int
+	RateLimited bool
+}
+
+// WithMaxRateLimitWait sets the total time a single request may spend
+// waiting for rate limits to reset or backoffs to expire
+func WithMaxRateLimitWait(d time.Duration) ClientOption {
+	return func(gc *GitHubClient) {
+		gc.maxWait = d
+	}
+}
+
+// WithMaxRetries sets how many times a request is retried
+func WithMaxRetries(n int) ClientOption {
+	return func(gc *GitHubClient) {
+		gc.maxRetries = n
+	}
+}
+
+// WithRetryNotify registers a callback invoked before every retry pause
+func WithRetryNotify(fn func(RetryNotice)) ClientOption {
+	return func(gc *GitHubClient) {
+		gc.notify = fn
+	}
+}
+
+// RateLimit returns the most recent budget reported by the API
+func (gc *GitHubClient) RateLimit() RateLimit {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.rateLimit
+}
+
+func (gc *GitHubClient) setRateLimit(rl RateLimit) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.rateLimit = rl
+}
+
+// makeRequest performs an API request, sleeping through rate limits and
+// retrying server errors with exponential backoff
+func (gc *GitHubClient) makeRequest(ctx context.Context, url string) (*http.Response, error) {
+	var waited time.Duration
+
+	for attempt := 1; ; attempt++ {
+		resp, err := gc.doRequest(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+
+		if rl, ok := parseRateLimit(resp.Header); ok {
+			gc.setRateLimit(rl)
+		}
+
+		wait, limitErr, retry := gc.retryDelay(resp, attempt)
+		if !retry {
+			return resp, nil
+		}
+
+		if attempt > gc.maxRetries || waited+wait > gc.maxWait {
+			if limitErr == nil {
+				// Out of retries for a server error; let the caller
+				// report the response as a regular API failure
+				return resp, nil
+			}
+			resp.Body.Close()
+			return nil, limitErr
+		}
+		resp.Body.Close()
+
+		if gc.notify != nil {
+			gc.notify(RetryNotice{
+				Attempt:     attempt,
+				Wait:        wait,
+				StatusCode:  resp.StatusCode,
+				RateLimited: limitErr != nil,
+			})
+		}
+		if err := gc.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		waited += wait
+	}
+}
+
+// retryDelay decides whether resp should be retried and after how long. A
+// non-nil error is returned for rate limited responses so the caller can
+// surface it once the wait ceiling is reached.
+func (gc *GitHubClient) retryDelay(resp *http.Response, attempt int) (time.Duration, *RateLimitError, bool) {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden:
+		exhausted := resp.Header.Get("X-RateLimit-Remaining") == "0"
+
+		var wait time.Duration
+		secondary := !exhausted
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), gc.now()); ok {
+			wait = d
+		} else if exhausted {
+			rl, _ := parseRateLimit(resp.Header)
+			// Add a second to absorb clock skew between us and the API
+			wait = max(rl.Reset.Sub(gc.now())+time.Second, 0)
+		} else if resp.StatusCode == http.StatusTooManyRequests || isSecondaryRateLimit(resp) {
+			wait = max(backoff(attempt), secondaryRateLimitBackoff)
+		} else {
+			// A plain permission error
+			return 0, nil, false
+		}
+
+		return wait, &RateLimitError{RateLimit: gc.RateLimit(), Wait: wait, Secondary: secondary}, true
+
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return backoff(attempt), nil, true
+	}
+
+	return 0, nil, false
+}
+
+// backoff returns the exponential delay for the given attempt number
+func backoff(attempt int) time.Duration {
+	d := initialBackoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+func parseRateLimit(h http.Header) (RateLimit, bool) {
+	limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return RateLimit{}, false
+	}
+	reset, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return RateLimit{}, false
+	}
+
+	return RateLimit{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}, true
+}
+
+// parseRetryAfter understands both forms of the Retry-After header: a number
+// of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// isSecondaryRateLimit inspects the body of a 403 response for GitHub's
+// secondary rate limit message. The body is restored for later readers.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+
+	message := strings.ToLower(string(body))
+	return strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse detection")
+}
+
+// sleepContext pauses for d, returning early with the context's error when
+// ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
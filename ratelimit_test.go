@@ -0,0 +1,222 @@
+package gitgrab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// scriptedClient replays a fixed list of responses, one per request
+type scriptedClient struct {
+	responses []func(w *httptest.ResponseRecorder)
+	calls     int
+}
+
+func (s *scriptedClient) Do(req *http.Request) (*http.Response, error) {
+	recorder := httptest.NewRecorder()
+	s.responses[s.calls](recorder)
+	s.calls++
+	return recorder.Result(), nil
+}
+
+func newTestRateLimitClient(script *scriptedClient, now time.Time, opts ...ClientOption) (*GitHubClient, *[]time.Duration) {
+	var slept []time.Duration
+	client := NewGitHubClientWithHTTPClient(GitHubToken("test-token"), script, opts...)
+	client.now = func() time.Time { return now }
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	return client, &slept
+}
+
+func okResponse(w *httptest.ResponseRecorder) {
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", "4999")
+	w.Header().Set("X-RateLimit-Reset", "1700000000")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`[]`))
+}
+
+func TestMakeRequest_WaitsForPrimaryRateLimitReset(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	script := &scriptedClient{responses: []func(w *httptest.ResponseRecorder){
+		func(w *httptest.ResponseRecorder) {
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(30*time.Second).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+		},
+		okResponse,
+	}}
+	client, slept := newTestRateLimitClient(script, now)
+
+	resp, err := client.makeRequest(context.Background(), "https://api.github.com/orgs/test/repos")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 31*time.Second {
+		t.Errorf("Expected a single 31s wait, got %v", *slept)
+	}
+	if rl := client.RateLimit(); rl.Remaining != 4999 || rl.Limit != 5000 {
+		t.Errorf("Expected remaining budget 4999/5000, got %+v", rl)
+	}
+}
+
+func TestMakeRequest_HonoursRetryAfter(t *testing.T) {
+	script := &scriptedClient{responses: []func(w *httptest.ResponseRecorder){
+		func(w *httptest.ResponseRecorder) {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+		okResponse,
+	}}
+	client, slept := newTestRateLimitClient(script, time.Now())
+
+	if _, err := client.makeRequest(context.Background(), "https://api.github.com/orgs/test/repos"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 7*time.Second {
+		t.Errorf("Expected a single 7s wait, got %v", *slept)
+	}
+}
+
+func TestMakeRequest_SecondaryRateLimitBody(t *testing.T) {
+	script := &scriptedClient{responses: []func(w *httptest.ResponseRecorder){
+		func(w *httptest.ResponseRecorder) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+		},
+		okResponse,
+	}}
+	client, slept := newTestRateLimitClient(script, time.Now())
+
+	if _, err := client.makeRequest(context.Background(), "https://api.github.com/orgs/test/repos"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(*slept) != 1 || (*slept)[0] != secondaryRateLimitBackoff {
+		t.Errorf("Expected a single %s wait, got %v", secondaryRateLimitBackoff, *slept)
+	}
+}
+
+func TestMakeRequest_BacksOffOnServerErrors(t *testing.T) {
+	serverError := func(w *httptest.ResponseRecorder) { w.WriteHeader(http.StatusBadGateway) }
+	script := &scriptedClient{responses: []func(w *httptest.ResponseRecorder){
+		serverError, serverError, serverError, okResponse,
+	}}
+	var notices []RetryNotice
+	client, slept := newTestRateLimitClient(script, time.Now(), WithRetryNotify(func(n RetryNotice) {
+		notices = append(notices, n)
+	}))
+
+	if _, err := client.makeRequest(context.Background(), "https://api.github.com/orgs/test/repos"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if len(*slept) != len(expected) {
+		t.Fatalf("Expected waits %v, got %v", expected, *slept)
+	}
+	for i := range expected {
+		if (*slept)[i] != expected[i] {
+			t.Errorf("Expected wait %d to be %s, got %s", i, expected[i], (*slept)[i])
+		}
+	}
+	if len(notices) != 3 || notices[0].RateLimited || notices[0].StatusCode != http.StatusBadGateway {
+		t.Errorf("Unexpected retry notices: %+v", notices)
+	}
+}
+
+func TestMakeRequest_FailsWhenWaitExceedsCeiling(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	script := &scriptedClient{responses: []func(w *httptest.ResponseRecorder){
+		func(w *httptest.ResponseRecorder) {
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Hour).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+		},
+	}}
+	client, slept := newTestRateLimitClient(script, now, WithMaxRateLimitWait(10*time.Minute))
+
+	_, err := client.FetchAllRepos(OrganizationName("testorg"))
+
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Expected RateLimitError, got %v", err)
+	}
+	if limitErr.Secondary {
+		t.Error("Expected a primary rate limit error")
+	}
+	if limitErr.RateLimit.Remaining != 0 {
+		t.Errorf("Expected remaining budget 0, got %d", limitErr.RateLimit.Remaining)
+	}
+	if len(*slept) != 0 {
+		t.Errorf("Expected no waits, got %v", *slept)
+	}
+}
+
+func TestMakeRequest_PermissionErrorNotRetried(t *testing.T) {
+	script := &scriptedClient{responses: []func(w *httptest.ResponseRecorder){
+		func(w *httptest.ResponseRecorder) {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+		},
+	}}
+	client, slept := newTestRateLimitClient(script, time.Now())
+
+	resp, err := client.makeRequest(context.Background(), "https://api.github.com/orgs/test/repos")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", resp.StatusCode)
+	}
+	if len(*slept) != 0 {
+		t.Errorf("Expected no waits, got %v", *slept)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if d, ok := parseRetryAfter("120", now); !ok || d != 2*time.Minute {
+		t.Errorf("Expected 2m, got %s (%v)", d, ok)
+	}
+	if d, ok := parseRetryAfter("Mon, 01 Jan 2024 00:00:30 GMT", now); !ok || d != 30*time.Second {
+		t.Errorf("Expected 30s, got %s (%v)", d, ok)
+	}
+	if _, ok := parseRetryAfter("", now); ok {
+		t.Error("Expected empty header to be rejected")
+	}
+}
+
+func TestMakeRequest_CanceledWhileWaiting(t *testing.T) {
+	script := &scriptedClient{responses: []func(w *httptest.ResponseRecorder){
+		func(w *httptest.ResponseRecorder) {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		},
+		okResponse,
+	}}
+	client := NewGitHubClientWithHTTPClient(GitHubToken("test-token"), script)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client.notify = func(RetryNotice) { cancel() }
+
+	start := time.Now()
+	_, err := client.makeRequest(ctx, "https://api.github.com/orgs/test/repos")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Error("Expected the wait to end when the context was canceled")
+	}
+}
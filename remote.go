@@ -0,0 +1,61 @@
+package gitgrab
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Policies for an existing repository whose origin remote is not the
+// repository being synced, accepted for CloneConfig.RemoteMismatch
+const (
+	// RemoteMismatchSkip leaves the repository alone with a warning
+	RemoteMismatchSkip = "skip"
+	// RemoteMismatchRewrite points origin at the expected remote and syncs
+	RemoteMismatchRewrite = "rewrite"
+	// RemoteMismatchFail reports the repository as failed
+	RemoteMismatchFail = "fail"
+)
+
+// ErrRemoteMismatch is returned for a repository whose origin remote points
+// somewhere else when the RemoteMismatchFail policy is in effect
+var ErrRemoteMismatch = errors.New("origin remote does not match the repository")
+
+// ValidateRemoteMismatch checks a remote mismatch policy name
+func ValidateRemoteMismatch(policy string) error {
+	switch policy {
+	case "", RemoteMismatchSkip, RemoteMismatchRewrite, RemoteMismatchFail:
+		return nil
+	}
+	return fmt.Errorf("invalid remote mismatch policy: %s", policy)
+}
+
+// normalizeRemote reduces a remote URL to a comparable form, so the SSH and
+// HTTPS URLs of a repository compare equal: host/owner/name, lower case,
+// without credentials, port or .git suffix. Local paths are cleaned instead.
+func normalizeRemote(remote string) string {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return ""
+	}
+
+	var host, path string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else if at, rest, ok := strings.Cut(remote, "@"); ok && !strings.Contains(at, "/") && strings.Contains(rest, ":") {
+		// scp-like syntax: git@github.com:owner/name.git
+		host, path, _ = strings.Cut(rest, ":")
+	} else {
+		return strings.TrimSuffix(filepath.Clean(remote), ".git")
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	return strings.ToLower(host + "/" + path)
+}
+
+// sameRemote reports whether two remote URLs refer to the same repository
+func sameRemote(a, b string) bool {
+	return normalizeRemote(a) == normalizeRemote(b)
+}
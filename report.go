@@ -0,0 +1,248 @@
+package gitgrab
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Status values used in reports
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+	// StatusCanceled marks repositories interrupted or never started
+	// because the sync was stopped
+	StatusCanceled = "canceled"
+)
+
+// ReportRecord is the machine-readable outcome of syncing one repository
+type ReportRecord struct {
+	Repository RepositoryName `json:"repository"`
+	Owner      string         `json:"owner,omitempty"`
+	Path       string         `json:"path"`
+	Action     Action         `json:"action"`
+	Status     string         `json:"status"`
+	OldHead    string         `json:"old_head,omitempty"`
+	NewHead    string         `json:"new_head,omitempty"`
+	Changed    bool           `json:"changed"`
+	Duration   float64        `json:"duration_seconds"`
+	Reason     string         `json:"reason,omitempty"`
+	Error      string         `json:"error,omitempty"`
+
+	// MovedFrom is the directory the repository was moved from
+	MovedFrom string `json:"moved_from,omitempty"`
+	// RenamedFrom is the previous default branch when the clone was
+	// migrated to a default branch renamed upstream
+	RenamedFrom string `json:"renamed_from,omitempty"`
+
+	// UpdatedBranches and DivergedBranches are only filled in when all
+	// branches are updated
+	UpdatedBranches  []string `json:"updated_branches,omitempty"`
+	DivergedBranches []string `json:"diverged_branches,omitempty"`
+}
+
+// ReportSummary totals the records of a report
+type ReportSummary struct {
+	Total     int     `json:"total"`
+	Succeeded int     `json:"succeeded"`
+	Failed    int     `json:"failed"`
+	Skipped   int     `json:"skipped"`
+	Canceled  int     `json:"canceled"`
+	Duration  float64 `json:"duration_seconds"`
+}
+
+// Report is the complete machine-readable outcome of a sync run
+type Report struct {
+	Repositories []ReportRecord `json:"repositories"`
+	Summary      ReportSummary  `json:"summary"`
+}
+
+// NewReportRecord converts a sync result for reporting
+func NewReportRecord(result SyncResult) ReportRecord {
+	record := ReportRecord{
+		Repository: result.Repository.Name,
+		Owner:      result.Repository.Owner.Login,
+		Path:       result.Path,
+		Action:     result.Action,
+		Status:     StatusOK,
+		OldHead:    result.OldHead,
+		NewHead:    result.NewHead,
+		Changed:    result.Changed(),
+		Duration:   result.Duration.Seconds(),
+
+		MovedFrom:   result.MovedFrom,
+		RenamedFrom: result.RenamedFrom,
+
+		UpdatedBranches:  result.UpdatedBranches,
+		DivergedBranches: result.DivergedBranches,
+	}
+	if result.Action == ActionSkip {
+		record.Status = StatusSkipped
+		record.Reason = result.Reason
+	}
+	if result.Err != nil {
+		record.Status = StatusFailed
+		if errors.Is(result.Err, context.Canceled) {
+			record.Status = StatusCanceled
+		}
+		record.Error = result.Err.Error()
+	}
+	return record
+}
+
+// SkippedRecord describes a repository that was deliberately not synced
+func SkippedRecord(config CloneConfig, reason string) ReportRecord {
+	entry := SkipEntry(config, reason)
+	return ReportRecord{
+		Repository: entry.Repository,
+		Owner:      entry.Owner,
+		Path:       entry.Path,
+		Action:     entry.Action,
+		Status:     StatusSkipped,
+		Reason:     entry.Reason,
+	}
+}
+
+// NewReport builds a report from records and the overall run time
+func NewReport(records []ReportRecord, duration time.Duration) Report {
+	report := Report{
+		Repositories: records,
+		Summary:      ReportSummary{Duration: duration.Seconds()},
+	}
+	if report.Repositories == nil {
+		report.Repositories = []ReportRecord{}
+	}
+	for _, record := range records {
+		report.Summary.add(record)
+	}
+	return report
+}
+
+func (s *ReportSummary) add(record ReportRecord) {
+	s.Total++
+	switch record.Status {
+	case StatusFailed:
+		s.Failed++
+	case StatusSkipped:
+		s.Skipped++
+	case StatusCanceled:
+		s.Canceled++
+	default:
+		s.Succeeded++
+	}
+}
+
+// WriteJSON writes the report as a single indented JSON document
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteJUnit writes the report as JUnit XML, with one test case per
+// repository, so CI systems can display sync failures
+func (r Report) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{
+		Name:     "gitgrab",
+		Tests:    r.Summary.Total,
+		Failures: r.Summary.Failed,
+		Skipped:  r.Summary.Skipped + r.Summary.Canceled,
+		Time:     junitTime(r.Summary.Duration),
+	}
+	for _, record := range r.Repositories {
+		tc := junitCase{
+			ClassName: record.Owner,
+			Name:      record.Repository.String(),
+			Time:      junitTime(record.Duration),
+		}
+		switch record.Status {
+		case StatusFailed:
+			tc.Failure = &junitMessage{
+				Message: record.Error,
+				Text:    fmt.Sprintf("%s %s: %s", record.Action, record.Path, record.Error),
+			}
+		case StatusSkipped:
+			tc.Skipped = &junitMessage{Message: record.Reason}
+		case StatusCanceled:
+			tc.Skipped = &junitMessage{Message: record.Error}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+func junitTime(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+// EventWriter streams a report as newline-delimited JSON: one repository
+// event per line as soon as it is known, then a summary event
+type EventWriter struct {
+	encoder *json.Encoder
+	summary ReportSummary
+}
+
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{encoder: json.NewEncoder(w)}
+}
+
+// WriteRecord emits a repository event
+func (ew *EventWriter) WriteRecord(record ReportRecord) error {
+	ew.summary.add(record)
+	return ew.encoder.Encode(struct {
+		Type string `json:"type"`
+		ReportRecord
+	}{"repository", record})
+}
+
+// WriteSummary emits the summary event for every record written so far
+func (ew *EventWriter) WriteSummary(duration time.Duration) error {
+	summary := ew.summary
+	summary.Duration = duration.Seconds()
+	return ew.encoder.Encode(struct {
+		Type string `json:"type"`
+		ReportSummary
+	}{"summary", summary})
+}
@@ -0,0 +1,146 @@
+package gitgrab
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testReportRecords() []ReportRecord {
+	owner := RepositoryOwner{Login: "acme"}
+	return []ReportRecord{
+		NewReportRecord(SyncResult{
+			Repository: Repository{Name: "api", Owner: owner},
+			Path:       "/src/api",
+			Action:     ActionPull,
+			OldHead:    "aaa",
+			NewHead:    "bbb",
+			Duration:   1500 * time.Millisecond,
+		}),
+		NewReportRecord(SyncResult{
+			Repository: Repository{Name: "web", Owner: owner},
+			Path:       "/src/web",
+			Action:     ActionClone,
+			Err:        errors.New("git clone failed: exit status 128"),
+		}),
+		SkippedRecord(CloneConfig{
+			Repository: Repository{Name: "old", Owner: owner},
+			TargetDir:  "/src",
+		}, "excluded by filters"),
+	}
+}
+
+func TestNewReportRecord(t *testing.T) {
+	records := testReportRecords()
+
+	if records[0].Status != StatusOK || !records[0].Changed || records[0].Duration != 1.5 {
+		t.Errorf("Unexpected record for successful pull: %+v", records[0])
+	}
+	if records[1].Status != StatusFailed || records[1].Error != "git clone failed: exit status 128" {
+		t.Errorf("Unexpected record for failed clone: %+v", records[1])
+	}
+	if records[2].Status != StatusSkipped || records[2].Action != ActionSkip || records[2].Reason != "excluded by filters" {
+		t.Errorf("Unexpected record for skipped repository: %+v", records[2])
+	}
+
+	// Repositories the sync itself left alone are skipped, not synced
+	record := NewReportRecord(SyncResult{Repository: Repository{Name: "api"}, Action: ActionSkip, Reason: "origin is elsewhere"})
+	if record.Status != StatusSkipped || record.Reason != "origin is elsewhere" {
+		t.Errorf("Unexpected record for repository skipped during sync: %+v", record)
+	}
+}
+
+func TestReport_WriteJSON(t *testing.T) {
+	report := NewReport(testReportRecords(), 2*time.Second)
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var decoded Report
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	expected := ReportSummary{Total: 3, Succeeded: 1, Failed: 1, Skipped: 1, Duration: 2}
+	if decoded.Summary != expected {
+		t.Errorf("Expected summary %+v, got %+v", expected, decoded.Summary)
+	}
+	if len(decoded.Repositories) != 3 || decoded.Repositories[0].NewHead != "bbb" {
+		t.Errorf("Unexpected repositories: %+v", decoded.Repositories)
+	}
+}
+
+func TestReport_WriteJSON_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewReport(nil, 0).WriteJSON(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"repositories": []`) {
+		t.Errorf("Expected an empty repositories array, got:\n%s", buf.String())
+	}
+}
+
+func TestReport_WriteJUnit(t *testing.T) {
+	report := NewReport(testReportRecords(), 2*time.Second)
+
+	var buf bytes.Buffer
+	if err := report.WriteJUnit(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		`<testsuite name="gitgrab" tests="3" failures="1" skipped="1" time="2.000">`,
+		`<testcase classname="acme" name="api" time="1.500"></testcase>`,
+		`<failure message="git clone failed: exit status 128">clone /src/web: git clone failed: exit status 128</failure>`,
+		`<skipped message="excluded by filters"></skipped>`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %s, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestEventWriter(t *testing.T) {
+	var buf bytes.Buffer
+	events := NewEventWriter(&buf)
+	for _, record := range testReportRecords() {
+		if err := events.WriteRecord(record); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := events.WriteSummary(time.Second); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 events, got %d:\n%s", len(lines), buf.String())
+	}
+
+	var first struct {
+		Type       string `json:"type"`
+		Repository string `json:"repository"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("Event is not valid JSON: %v", err)
+	}
+	if first.Type != "repository" || first.Repository != "api" {
+		t.Errorf("Unexpected first event: %s", lines[0])
+	}
+
+	var last struct {
+		Type string `json:"type"`
+		ReportSummary
+	}
+	if err := json.Unmarshal([]byte(lines[3]), &last); err != nil {
+		t.Fatalf("Event is not valid JSON: %v", err)
+	}
+	if last.Type != "summary" || last.Total != 3 || last.Failed != 1 || last.Skipped != 1 {
+		t.Errorf("Unexpected summary event: %s", lines[3])
+	}
+}
@@ -0,0 +1,189 @@
+package gitgrab
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// AuthenticatedUser is the user name that refers to the owner of the token.
+// Listing its repositories includes private ones.
+const AuthenticatedUser = "@me"
+
+// DefaultAffiliation limits the authenticated user's repositories to the ones
+// they own, mirroring what /users/{name}/repos returns for other users
+const DefaultAffiliation = "owner"
+
+// SourceKind distinguishes the kinds of accounts repositories are listed from
+type SourceKind int
+
+const (
+	SourceOrganization SourceKind = iota
+	SourceUser
+	SourceTeam
+)
+
+func (k SourceKind) String() string {
+	switch k {
+	case SourceOrganization:
+		return "organization"
+	case SourceUser:
+		return "user"
+	case SourceTeam:
+		return "team"
+	default:
+		return "unknown"
+	}
+}
+
+// Source identifies an account, or a team within an organization, whose
+// repositories are synced
+type Source struct {
+	Kind SourceKind
+	// Name is the organization or user name; for teams, the organization
+	Name string
+	// Affiliation selects which of the authenticated user's repositories
+	// are listed, e.g. "owner,collaborator". Ignored for other sources.
+	Affiliation string
+	// Team is the team slug for team sources
+	Team        string
+	TeamOptions TeamRepoOptions
+}
+
+func OrganizationSource(name OrganizationName) Source {
+	return Source{Kind: SourceOrganization, Name: name.String()}
+}
+
+func UserSource(name string) Source {
+	return Source{Kind: SourceUser, Name: name}
+}
+
+func TeamSource(org OrganizationName, slug string, opts TeamRepoOptions) Source {
+	return Source{Kind: SourceTeam, Name: org.String(), Team: slug, TeamOptions: opts}
+}
+
+func (s Source) String() string {
+	if s.Kind == SourceTeam {
+		return fmt.Sprintf("%s %s/%s", s.Kind, s.Name, s.Team)
+	}
+	return fmt.Sprintf("%s %s", s.Kind, s.Name)
+}
+
+func (s Source) IsAuthenticatedUser() bool {
+	return s.Kind == SourceUser && s.Name == AuthenticatedUser
+}
+
+// User is the subset of a GitHub user account that gitgrab needs
+type User struct {
+	Login string `json:"login"`
+}
+
+// Organization is the subset of a GitHub organization that gitgrab needs
+type Organization struct {
+	Login OrganizationName `json:"login"`
+}
+
+// FetchAuthenticatedUser returns the account the token belongs to
+func (gc *GitHubClient) FetchAuthenticatedUser() (User, error) {
+	return gc.FetchAuthenticatedUserContext(context.Background())
+}
+
+// FetchAuthenticatedUserContext is like FetchAuthenticatedUser but stops
+// when ctx is done
+func (gc *GitHubClient) FetchAuthenticatedUserContext(ctx context.Context) (User, error) {
+	var user User
+	_, err := getJSON(ctx, gc, gc.baseURL+"/user", &user)
+	return user, err
+}
+
+// FetchUserRepos lists the public repositories owned by a user
+func (gc *GitHubClient) FetchUserRepos(user string) ([]Repository, error) {
+	return gc.FetchUserReposContext(context.Background(), user)
+}
+
+// FetchUserReposContext is like FetchUserRepos but stops when ctx is done
+func (gc *GitHubClient) FetchUserReposContext(ctx context.Context, user string) ([]Repository, error) {
+	endpoint := fmt.Sprintf("%s/users/%s/repos?per_page=%d&type=owner", gc.baseURL, url.PathEscape(user), perPage)
+	return fetchPages[Repository](ctx, gc, endpoint)
+}
+
+// FetchAuthenticatedUserRepos lists the repositories of the token owner,
+// including private ones, limited by affiliation
+func (gc *GitHubClient) FetchAuthenticatedUserRepos(affiliation string) ([]Repository, error) {
+	return gc.FetchAuthenticatedUserReposContext(context.Background(), affiliation)
+}
+
+// FetchAuthenticatedUserReposContext is like FetchAuthenticatedUserRepos but
+// stops when ctx is done
+func (gc *GitHubClient) FetchAuthenticatedUserReposContext(ctx context.Context, affiliation string) ([]Repository, error) {
+	if affiliation == "" {
+		affiliation = DefaultAffiliation
+	}
+	endpoint := fmt.Sprintf("%s/user/repos?per_page=%d&affiliation=%s", gc.baseURL, perPage, url.QueryEscape(affiliation))
+	return fetchPages[Repository](ctx, gc, endpoint)
+}
+
+// FetchUserOrgs lists the organizations the token owner belongs to
+func (gc *GitHubClient) FetchUserOrgs() ([]Organization, error) {
+	return gc.FetchUserOrgsContext(context.Background())
+}
+
+// FetchUserOrgsContext is like FetchUserOrgs but stops when ctx is done
+func (gc *GitHubClient) FetchUserOrgsContext(ctx context.Context) ([]Organization, error) {
+	endpoint := fmt.Sprintf("%s/user/orgs?per_page=%d", gc.baseURL, perPage)
+	return fetchPages[Organization](ctx, gc, endpoint)
+}
+
+// FetchSourceRepos lists the repositories of any kind of source
+func (gc *GitHubClient) FetchSourceRepos(src Source) ([]Repository, error) {
+	return gc.FetchSourceReposContext(context.Background(), src)
+}
+
+// FetchSourceReposContext is like FetchSourceRepos but stops when ctx is done
+func (gc *GitHubClient) FetchSourceReposContext(ctx context.Context, src Source) ([]Repository, error) {
+	switch {
+	case src.Kind == SourceOrganization:
+		return gc.FetchAllReposContext(ctx, OrganizationName(src.Name))
+	case src.IsAuthenticatedUser():
+		return gc.FetchAuthenticatedUserReposContext(ctx, src.Affiliation)
+	case src.Kind == SourceUser:
+		return gc.FetchUserReposContext(ctx, src.Name)
+	case src.Kind == SourceTeam:
+		return gc.FetchTeamReposContext(ctx, OrganizationName(src.Name), src.Team, src.TeamOptions)
+	default:
+		return nil, fmt.Errorf("unsupported source: %s", src)
+	}
+}
+
+// ExpandOrganizations returns a source for each organization the token owner
+// belongs to, skipping organizations already present in existing
+func (gc *GitHubClient) ExpandOrganizations(existing []Source) ([]Source, error) {
+	return gc.ExpandOrganizationsContext(context.Background(), existing)
+}
+
+// ExpandOrganizationsContext is like ExpandOrganizations but stops when ctx
+// is done
+func (gc *GitHubClient) ExpandOrganizationsContext(ctx context.Context, existing []Source) ([]Source, error) {
+	orgs, err := gc.FetchUserOrgsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, src := range existing {
+		if src.Kind == SourceOrganization {
+			seen[strings.ToLower(src.Name)] = true
+		}
+	}
+
+	var sources []Source
+	for _, org := range orgs {
+		if seen[strings.ToLower(org.Login.String())] {
+			continue
+		}
+		seen[strings.ToLower(org.Login.String())] = true
+		sources = append(sources, OrganizationSource(org.Login))
+	}
+	return sources, nil
+}
@@ -0,0 +1,100 @@
+package gitgrab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// routedClient answers requests by path with a JSON encoded value
+func routedClient(t *testing.T, routes map[string]any, requested *[]string) *GitHubClient {
+	mockClient := &mockHTTPClient{
+		doFunc: func(req *http.Request) (*http.Response, error) {
+			*requested = append(*requested, req.URL.RequestURI())
+
+			recorder := httptest.NewRecorder()
+			body, ok := routes[req.URL.Path]
+			if !ok {
+				t.Errorf("Unexpected request to %s", req.URL)
+				recorder.WriteHeader(http.StatusNotFound)
+				return recorder.Result(), nil
+			}
+			recorder.WriteHeader(http.StatusOK)
+			json.NewEncoder(recorder).Encode(body)
+			return recorder.Result(), nil
+		},
+	}
+	return NewGitHubClientWithHTTPClient(GitHubToken("test-token"), mockClient)
+}
+
+func TestGitHubClient_FetchSourceRepos(t *testing.T) {
+	routes := map[string]any{
+		"/orgs/acme/repos":   []Repository{{Name: "org-repo"}},
+		"/users/alice/repos": []Repository{{Name: "alice-repo"}},
+		"/user/repos":        []Repository{{Name: "my-private-repo", Private: true}},
+	}
+
+	tests := []struct {
+		source       Source
+		expectedRepo RepositoryName
+		expectedURI  string
+	}{
+		{OrganizationSource("acme"), "org-repo", "/orgs/acme/repos?per_page=100&type=all"},
+		{UserSource("alice"), "alice-repo", "/users/alice/repos?per_page=100&type=owner"},
+		{UserSource(AuthenticatedUser), "my-private-repo", "/user/repos?per_page=100&affiliation=owner"},
+		{Source{Kind: SourceUser, Name: AuthenticatedUser, Affiliation: "owner,collaborator"}, "my-private-repo", "/user/repos?per_page=100&affiliation=owner%2Ccollaborator"},
+	}
+
+	for _, tt := range tests {
+		var requested []string
+		client := routedClient(t, routes, &requested)
+
+		repos, err := client.FetchSourceRepos(tt.source)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", tt.source, err)
+		}
+		if len(repos) != 1 || repos[0].Name != tt.expectedRepo {
+			t.Errorf("Expected %s for %s, got %+v", tt.expectedRepo, tt.source, repos)
+		}
+		if len(requested) != 1 || requested[0] != tt.expectedURI {
+			t.Errorf("Expected request to %s, got %v", tt.expectedURI, requested)
+		}
+	}
+}
+
+func TestGitHubClient_ExpandOrganizations(t *testing.T) {
+	var requested []string
+	client := routedClient(t, map[string]any{
+		"/user/orgs": []Organization{{Login: "acme"}, {Login: "Widgets"}, {Login: "tools"}},
+	}, &requested)
+
+	existing := []Source{OrganizationSource("ACME"), UserSource("widgets")}
+	sources, err := client.ExpandOrganizations(existing)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []string{"Widgets", "tools"}
+	if len(sources) != len(expected) {
+		t.Fatalf("Expected %v, got %+v", expected, sources)
+	}
+	for i, src := range sources {
+		if src.Kind != SourceOrganization || src.Name != expected[i] {
+			t.Errorf("Expected organization %s, got %s", expected[i], src)
+		}
+	}
+}
+
+func TestGitHubClient_FetchAuthenticatedUser(t *testing.T) {
+	var requested []string
+	client := routedClient(t, map[string]any{"/user": User{Login: "octocat"}}, &requested)
+
+	user, err := client.FetchAuthenticatedUser()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if user.Login != "octocat" {
+		t.Errorf("Expected login octocat, got %s", user.Login)
+	}
+}
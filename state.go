@@ -0,0 +1,115 @@
+package gitgrab
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// StateDir is the directory below the target directory where gitgrab keeps
+// what it knows about the repositories it synced
+const StateDir = ".gitgrab"
+
+// stateFileName is the state file inside StateDir
+const stateFileName = "state.json"
+
+// RepoState is what gitgrab remembers about a repository it synced
+type RepoState struct {
+	FullName string `json:"full_name"`
+	// Path is relative to the target directory, so the whole tree can be
+	// moved
+	Path string `json:"path"`
+	// Archived is set once the repository was synced after being archived
+	Archived bool `json:"archived,omitempty"`
+}
+
+// State maps repository IDs to where each repository was last synced. IDs
+// survive renames and transfers, so a repository can be found again under
+// its old name.
+type State struct {
+	Repositories map[int64]RepoState `json:"repositories"`
+
+	root string
+	mu   sync.Mutex
+}
+
+// LoadState reads the state kept in root. A missing state file gives an
+// empty state.
+func LoadState(root string) (*State, error) {
+	state := &State{Repositories: make(map[int64]RepoState), root: root}
+
+	data, err := os.ReadFile(state.path())
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("%s: %w", state.path(), err)
+	}
+	if state.Repositories == nil {
+		state.Repositories = make(map[int64]RepoState)
+	}
+	return state, nil
+}
+
+func (s *State) path() string {
+	return filepath.Join(s.root, StateDir, stateFileName)
+}
+
+// Previous returns where a repository was last synced, with an absolute
+// path, or nil when it is not known
+func (s *State) Previous(repo Repository) *RepoState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	known, ok := s.Repositories[repo.ID]
+	if repo.ID == 0 || !ok {
+		return nil
+	}
+	known.Path = filepath.Join(s.root, filepath.FromSlash(known.Path))
+	return &known
+}
+
+// Record remembers where a repository was synced. Results of repositories
+// that were skipped, failed or have no ID are ignored, so the last known
+// location is kept.
+func (s *State) Record(result SyncResult) {
+	if result.Repository.ID == 0 || result.Action == ActionSkip || result.Err != nil {
+		return
+	}
+	path, err := filepath.Rel(s.root, result.Path)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Repositories[result.Repository.ID] = RepoState{
+		FullName: result.Repository.FullName,
+		Path:     filepath.ToSlash(path),
+		Archived: result.Repository.Archived,
+	}
+}
+
+// Save writes the state to disk, replacing the previous file in one step
+func (s *State) Save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path()), 0755); err != nil {
+		return err
+	}
+	tmp := s.path() + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path())
+}
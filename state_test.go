@@ -0,0 +1,137 @@
+package gitgrab
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestState_RoundTrip(t *testing.T) {
+	root := t.TempDir()
+
+	state, err := LoadState(root)
+	if err != nil {
+		t.Fatalf("Expected a missing state file to give an empty state, got %v", err)
+	}
+
+	repo := Repository{ID: 42, Name: "api", FullName: "acme/api"}
+	state.Record(SyncResult{Repository: repo, Path: filepath.Join(root, "acme", "api"), Action: ActionClone})
+	// Skipped repositories and repositories without an ID are not recorded
+	state.Record(SyncResult{Repository: Repository{ID: 7, Name: "web"}, Path: filepath.Join(root, "web"), Action: ActionSkip})
+	state.Record(SyncResult{Repository: Repository{Name: "docs"}, Path: filepath.Join(root, "docs"), Action: ActionClone})
+	if err := state.Save(); err != nil {
+		t.Fatalf("Unexpected error saving state: %v", err)
+	}
+
+	loaded, err := LoadState(root)
+	if err != nil {
+		t.Fatalf("Unexpected error loading state: %v", err)
+	}
+	if len(loaded.Repositories) != 1 || loaded.Repositories[42].Path != "acme/api" {
+		t.Errorf("Expected only acme/api with a relative path, got %+v", loaded.Repositories)
+	}
+
+	previous := loaded.Previous(Repository{ID: 42, Name: "gateway", FullName: "acme/gateway"})
+	if previous == nil || previous.FullName != "acme/api" || previous.Path != filepath.Join(root, "acme", "api") {
+		t.Errorf("Expected acme/api under %s, got %+v", root, previous)
+	}
+	if loaded.Previous(Repository{ID: 7}) != nil || loaded.Previous(Repository{}) != nil {
+		t.Error("Expected unknown repositories to have no previous location")
+	}
+}
+
+func TestLoadState_Invalid(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, StateDir), 0755)
+	os.WriteFile(filepath.Join(root, StateDir, "state.json"), []byte("{"), 0644)
+
+	if _, err := LoadState(root); err == nil {
+		t.Error("Expected an error for an invalid state file")
+	}
+}
+
+func TestSyncRepo_FollowsRenamedRepository(t *testing.T) {
+	upstream := t.TempDir()
+	source := filepath.Join(upstream, "acme", "old")
+	initRepoOnBranch(t, source, "main")
+
+	targetDir := t.TempDir()
+	config := CloneConfig{
+		Repository: Repository{ID: 42, Name: "old", FullName: "acme/old", CloneURL: HTTPURL(source), DefaultBranch: "main"},
+		TargetDir:  targetDir,
+		Method:     CloneMethodHTTP,
+		Reporter:   ReporterFunc(func(Event) {}),
+	}
+	state, _ := LoadState(targetDir)
+	state.Record(SyncRepo(config))
+
+	// The repository is renamed upstream
+	renamed := filepath.Join(upstream, "acme", "new")
+	if err := os.Rename(source, renamed); err != nil {
+		t.Fatalf("Failed to rename upstream: %v", err)
+	}
+	config.Repository = Repository{ID: 42, Name: "new", FullName: "acme/new", CloneURL: HTTPURL(renamed), DefaultBranch: "main"}
+	config.Previous = state.Previous(config.Repository)
+
+	var kinds []EventKind
+	config.Reporter = ReporterFunc(func(e Event) { kinds = append(kinds, e.Kind) })
+	result := SyncRepo(config)
+
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+	if result.Action != ActionPull || result.MovedFrom != filepath.Join(targetDir, "old") {
+		t.Errorf("Expected a pull after moving from the old directory, got %+v", result)
+	}
+	if !slices.Contains(kinds, EventMoved) || !slices.Contains(kinds, EventRemoteRewritten) {
+		t.Errorf("Expected the move and the new remote to be reported, got %v", kinds)
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "old")); !os.IsNotExist(err) {
+		t.Errorf("Expected the old directory to be gone, got %v", err)
+	}
+	output, _ := exec.Command("git", "-C", config.RepoPath(), "remote", "get-url", "origin").Output()
+	if origin := strings.TrimSpace(string(output)); origin != renamed {
+		t.Errorf("Expected origin %s, got %s", renamed, origin)
+	}
+}
+
+func TestSyncer_MovesBeforeReusingOldName(t *testing.T) {
+	upstream := t.TempDir()
+	source := filepath.Join(upstream, "acme", "old")
+	initRepoOnBranch(t, source, "main")
+
+	targetDir := t.TempDir()
+	renamedConfig := CloneConfig{
+		Repository: Repository{ID: 1, Name: "old", FullName: "acme/old", CloneURL: HTTPURL(source), DefaultBranch: "main"},
+		TargetDir:  targetDir,
+		Method:     CloneMethodHTTP,
+		Reporter:   ReporterFunc(func(Event) {}),
+	}
+	state, _ := LoadState(targetDir)
+	state.Record(SyncRepo(renamedConfig))
+
+	// acme/old is renamed to acme/new, and a new acme/old is created
+	renamed := filepath.Join(upstream, "acme", "new")
+	os.Rename(source, renamed)
+	initRepoOnBranch(t, source, "main")
+
+	renamedConfig.Repository = Repository{ID: 1, Name: "new", FullName: "acme/new", CloneURL: HTTPURL(renamed), DefaultBranch: "main"}
+	renamedConfig.Previous = state.Previous(renamedConfig.Repository)
+	newConfig := renamedConfig
+	newConfig.Repository = Repository{ID: 2, Name: "old", FullName: "acme/old", CloneURL: HTTPURL(source), DefaultBranch: "main"}
+	newConfig.Previous = nil
+
+	// The new repository comes first, but must not touch the old directory
+	// before the renamed one has moved out
+	summary := NewSyncer(2, ReporterFunc(func(Event) {})).Sync([]CloneConfig{newConfig, renamedConfig})
+
+	if created := summary.Results[0]; created.Err != nil || created.Action != ActionClone {
+		t.Errorf("Expected the new repository to be cloned, got %+v", created)
+	}
+	if moved := summary.Results[1]; moved.Err != nil || moved.MovedFrom != filepath.Join(targetDir, "old") {
+		t.Errorf("Expected the renamed repository to be moved, got %+v", moved)
+	}
+}
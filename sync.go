@@ -0,0 +1,324 @@
+package gitgrab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultJobs is the number of repositories synced concurrently when no
+// explicit job count is given
+const DefaultJobs = 4
+
+// SyncResult records the outcome of cloning or updating a single repository
+type SyncResult struct {
+	Repository Repository
+	Path       string
+	Action     Action
+	// Reason explains why the repository was skipped
+	Reason string
+	// OldHead and NewHead are the commits checked out before and after the
+	// sync; OldHead is empty for fresh clones
+	OldHead  string
+	NewHead  string
+	Duration time.Duration
+	Err      error
+
+	// MovedFrom is the directory the repository was moved from, after it
+	// was renamed or transferred
+	MovedFrom string
+	// RenamedFrom is the previous default branch when the clone was
+	// migrated to a default branch renamed upstream
+	RenamedFrom string
+
+	// UpdatedBranches and DivergedBranches list the branches other than
+	// the checked out one that were fast-forwarded, or could not be
+	// because they have diverged from their upstream
+	UpdatedBranches  []string
+	DivergedBranches []string
+}
+
+// Changed reports whether the checked out commit moved
+func (r SyncResult) Changed() bool {
+	return r.OldHead != r.NewHead
+}
+
+// SyncSummary aggregates the results of a sync run, in input order
+type SyncSummary struct {
+	Results   []SyncResult
+	Succeeded int
+	Failed    int
+	// Skipped counts repositories left alone, such as checkouts of another
+	// repository
+	Skipped int
+	// Canceled counts repositories that were interrupted or never started
+	// because the sync was stopped
+	Canceled int
+	Duration time.Duration
+}
+
+// Syncer clones or updates many repositories with a bounded pool of workers.
+// Events for each repository are buffered and reported in input order, so
+// progress from concurrent jobs never interleaves.
+type Syncer struct {
+	Jobs     int
+	Reporter Reporter
+	// Timeout limits the time spent on each repository; zero means no limit
+	Timeout time.Duration
+	// OnResult, when set, is called with each result in input order as soon
+	// as it is available, which allows streaming reports
+	OnResult func(SyncResult)
+
+	sync func(context.Context, CloneConfig) SyncResult
+}
+
+func NewSyncer(jobs int, reporter Reporter) *Syncer {
+	return &Syncer{
+		Jobs:     jobs,
+		Reporter: reporter,
+		sync:     SyncRepoContext,
+	}
+}
+
+func (s *Syncer) jobs(n int) int {
+	jobs := s.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > n {
+		jobs = n
+	}
+	return jobs
+}
+
+func (s *Syncer) reporter() Reporter {
+	if s.Reporter == nil {
+		return NewConsoleReporter(os.Stdout)
+	}
+	return s.Reporter
+}
+
+// Sync processes every config and blocks until all of them have finished
+func (s *Syncer) Sync(configs []CloneConfig) SyncSummary {
+	return s.SyncContext(context.Background(), configs)
+}
+
+// SyncContext is like Sync but stops early when ctx is done: repositories
+// not yet started are left alone and the ones in progress are interrupted,
+// with partial clones removed. Every config still gets a result.
+func (s *Syncer) SyncContext(ctx context.Context, configs []CloneConfig) SyncSummary {
+	start := time.Now()
+	summary := SyncSummary{Results: make([]SyncResult, len(configs))}
+	if len(configs) == 0 {
+		return summary
+	}
+
+	syncRepo := s.sync
+	if syncRepo == nil {
+		syncRepo = SyncRepoContext
+	}
+
+	logs := make([]eventLog, len(configs))
+	notStarted := make([]bool, len(configs))
+	done := make([]chan struct{}, len(configs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	skip := func(i int) {
+		notStarted[i] = true
+		summary.Results[i] = SyncResult{
+			Repository: configs[i].Repository,
+			Path:       configs[i].RepoPath(),
+			Err:        ctx.Err(),
+		}
+		close(done[i])
+	}
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < s.jobs(len(configs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				if ctx.Err() != nil {
+					skip(i)
+					continue
+				}
+
+				config := configs[i]
+				config.Reporter = &logs[i]
+				repoCtx, cancel := ctx, context.CancelFunc(func() {})
+				if s.Timeout > 0 {
+					repoCtx, cancel = context.WithTimeout(ctx, s.Timeout)
+				}
+				result := syncRepo(repoCtx, config)
+				cancel()
+				result.Repository = config.Repository
+				summary.Results[i] = result
+				close(done[i])
+			}
+		}()
+	}
+
+	// Repositories sharing a directory would clobber each other's clones
+	// and remotes, so none of them is synced
+	conflicts := PathConflicts(configs)
+	order, moves := syncOrder(configs)
+	for n := len(order) - 1; n >= 0; n-- {
+		i := order[n]
+		if conflicts[i] == nil {
+			continue
+		}
+		summary.Results[i] = SyncResult{
+			Repository: configs[i].Repository,
+			Path:       configs[i].RepoPath(),
+			Action:     ActionSkip,
+			Err:        conflicts[i],
+		}
+		close(done[i])
+		order = slices.Delete(order, n, n+1)
+		if n < moves {
+			moves--
+		}
+	}
+	go func() {
+		defer close(work)
+		for n, i := range order {
+			select {
+			case work <- i:
+			case <-ctx.Done():
+				for _, i := range order[n:] {
+					skip(i)
+				}
+				return
+			}
+			if n < moves {
+				<-done[i]
+			}
+		}
+	}()
+
+	reporter := s.reporter()
+	for i, config := range configs {
+		<-done[i]
+		result := summary.Results[i]
+		position := func(e Event) Event {
+			e.Index, e.Total = i+1, len(configs)
+			return e
+		}
+
+		if notStarted[i] {
+			summary.Canceled++
+		} else {
+			reporter.Report(position(Event{Kind: EventStarted, Repository: config.Repository, Path: result.Path, Action: result.Action}))
+			for _, e := range logs[i] {
+				reporter.Report(position(e))
+			}
+			if result.Err != nil {
+				reporter.Report(position(Event{Kind: EventFailed, Repository: config.Repository, Path: result.Path, Action: result.Action, Err: result.Err}))
+				if errors.Is(result.Err, context.Canceled) {
+					summary.Canceled++
+				} else {
+					summary.Failed++
+				}
+			} else if result.Action == ActionSkip {
+				summary.Skipped++
+			} else {
+				reporter.Report(position(Event{Kind: EventSynced, Repository: config.Repository, Path: result.Path, Action: result.Action}))
+				summary.Succeeded++
+			}
+		}
+
+		if s.OnResult != nil {
+			s.OnResult(result)
+		}
+	}
+
+	wg.Wait()
+	summary.Duration = time.Since(start)
+	return summary
+}
+
+// ErrPathConflict is returned for repositories that would be synced into the
+// same directory
+var ErrPathConflict = errors.New("repositories share a directory")
+
+// PathConflicts returns an error for each config whose repository would be
+// synced into the same directory as another one, such as when a layout
+// template leaves out {owner} and two owners have a repository of the same
+// name, and nil for the others. Directories are compared regardless of case,
+// as case-insensitive filesystems would.
+func PathConflicts(configs []CloneConfig) []error {
+	byPath := make(map[string][]int)
+	for i, config := range configs {
+		key := strings.ToLower(filepath.Clean(config.RepoPath()))
+		byPath[key] = append(byPath[key], i)
+	}
+
+	name := func(repo Repository) string {
+		if repo.FullName != "" {
+			return repo.FullName
+		}
+		return repo.Name.String()
+	}
+	errs := make([]error, len(configs))
+	for _, shared := range byPath {
+		if len(shared) < 2 {
+			continue
+		}
+		for _, i := range shared {
+			var others []string
+			for _, j := range shared {
+				if j != i {
+					others = append(others, name(configs[j].Repository))
+				}
+			}
+			errs[i] = fmt.Errorf("%w: %s is also the directory of %s; use a layout that tells them apart",
+				ErrPathConflict, configs[i].RepoPath(), strings.Join(others, ", "))
+		}
+	}
+	return errs
+}
+
+// syncOrder returns the order to start syncing configs in, and how many of
+// them, at the start, must run one at a time. Repositories that move to a
+// new directory after a rename or transfer go first, so no other repository,
+// such as a new one taking over the old name, is synced in a directory that
+// is still being moved away. A move into a directory another move empties
+// waits for that move.
+func syncOrder(configs []CloneConfig) ([]int, int) {
+	var moving, rest []int
+	for i, config := range configs {
+		if config.Previous != nil && filepath.Clean(config.Previous.Path) != filepath.Clean(config.RepoPath()) {
+			moving = append(moving, i)
+		} else {
+			rest = append(rest, i)
+		}
+	}
+
+	var order []int
+	for len(moving) > 0 {
+		next := 0
+		for n, i := range moving {
+			target := filepath.Clean(configs[i].RepoPath())
+			vacated := slices.ContainsFunc(moving, func(j int) bool {
+				return j != i && filepath.Clean(configs[j].Previous.Path) == target
+			})
+			if !vacated {
+				next = n
+				break
+			}
+		}
+		order = append(order, moving[next])
+		moving = slices.Delete(moving, next, next+1)
+	}
+	moves := len(order)
+	return append(order, rest...), moves
+}
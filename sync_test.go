@@ -0,0 +1,184 @@
+package gitgrab
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testConfigs(names ...string) []CloneConfig {
+	configs := make([]CloneConfig, 0, len(names))
+	for _, name := range names {
+		configs = append(configs, CloneConfig{
+			Repository: Repository{Name: RepositoryName(name)},
+			TargetDir:  "/tmp/unused",
+		})
+	}
+	return configs
+}
+
+func TestSyncer_Sync_OrderedOutput(t *testing.T) {
+	var out bytes.Buffer
+	syncer := NewSyncer(3, NewConsoleReporter(&out))
+	syncer.sync = func(ctx context.Context, config CloneConfig) SyncResult {
+		// Later repositories finish first to exercise ordering
+		switch config.Repository.Name {
+		case "repo1":
+			time.Sleep(30 * time.Millisecond)
+		case "repo2":
+			time.Sleep(10 * time.Millisecond)
+		}
+		config.Reporter.Report(Event{Kind: EventWarning, Repository: config.Repository, Message: "working on " + config.Repository.Name.String()})
+		if config.Repository.Name == "repo2" {
+			return SyncResult{Err: errors.New("failed to clone repo2")}
+		}
+		return SyncResult{Action: ActionClone}
+	}
+
+	summary := syncer.Sync(testConfigs("repo1", "repo2", "repo3"))
+
+	if summary.Succeeded != 2 {
+		t.Errorf("Expected 2 successes, got %d", summary.Succeeded)
+	}
+	if summary.Failed != 1 {
+		t.Errorf("Expected 1 failure, got %d", summary.Failed)
+	}
+	if summary.Results[1].Err == nil {
+		t.Error("Expected second result to carry an error")
+	}
+
+	expected := strings.Join([]string{
+		"[1/3] Cloning repo1...",
+		"  Warning: working on repo1",
+		"  ✓ Successfully cloned repo1",
+		"[2/3] Cloning repo2...",
+		"  Warning: working on repo2",
+		"  ✗ failed to clone repo2",
+		"[3/3] Cloning repo3...",
+		"  Warning: working on repo3",
+		"  ✓ Successfully cloned repo3",
+		"",
+	}, "\n")
+	if out.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestSyncer_Sync_BoundedConcurrency(t *testing.T) {
+	var running, peak int32
+	syncer := NewSyncer(2, NewConsoleReporter(&bytes.Buffer{}))
+	syncer.sync = func(ctx context.Context, config CloneConfig) SyncResult {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return SyncResult{}
+	}
+
+	summary := syncer.Sync(testConfigs("a", "b", "c", "d", "e", "f"))
+
+	if summary.Succeeded != 6 {
+		t.Errorf("Expected 6 successes, got %d", summary.Succeeded)
+	}
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent jobs, got %d", peak)
+	}
+}
+
+func TestSyncer_Sync_Empty(t *testing.T) {
+	syncer := NewSyncer(0, NewConsoleReporter(&bytes.Buffer{}))
+	summary := syncer.Sync(nil)
+
+	if len(summary.Results) != 0 || summary.Succeeded != 0 || summary.Failed != 0 {
+		t.Errorf("Expected empty summary, got %+v", summary)
+	}
+}
+
+func TestSyncRepo_ReportsHeads(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "upstream")
+	initRepoOnBranch(t, source, "main")
+
+	config := CloneConfig{
+		Repository: Repository{Name: "upstream", CloneURL: HTTPURL(source), DefaultBranch: "main"},
+		TargetDir:  t.TempDir(),
+		Method:     CloneMethodHTTP,
+		Reporter:   ReporterFunc(func(Event) {}),
+	}
+
+	cloned := SyncRepo(config)
+	if cloned.Err != nil {
+		t.Fatalf("Unexpected error cloning: %v", cloned.Err)
+	}
+	if cloned.Action != ActionClone || cloned.OldHead != "" || cloned.NewHead == "" {
+		t.Errorf("Unexpected clone result: %+v", cloned)
+	}
+
+	exec.Command("git", "-C", source, "-c", "user.email=test@example.com", "-c", "user.name=Test User",
+		"commit", "--allow-empty", "-m", "Second commit").Run()
+
+	pulled := SyncRepo(config)
+	if pulled.Err != nil {
+		t.Fatalf("Unexpected error pulling: %v", pulled.Err)
+	}
+	if pulled.Action != ActionPull {
+		t.Errorf("Expected pull, got %s", pulled.Action)
+	}
+	if pulled.OldHead != cloned.NewHead {
+		t.Errorf("Expected old head %s, got %s", cloned.NewHead, pulled.OldHead)
+	}
+	if !pulled.Changed() {
+		t.Error("Expected pull to move HEAD")
+	}
+}
+
+func TestSyncer_SyncContext_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var called int32
+	var reported []SyncResult
+	syncer := NewSyncer(2, NewConsoleReporter(&bytes.Buffer{}))
+	syncer.OnResult = func(r SyncResult) { reported = append(reported, r) }
+	syncer.sync = func(ctx context.Context, config CloneConfig) SyncResult {
+		atomic.AddInt32(&called, 1)
+		return SyncResult{}
+	}
+
+	summary := syncer.SyncContext(ctx, testConfigs("a", "b", "c"))
+
+	if called != 0 {
+		t.Errorf("Expected no repository to start, %d did", called)
+	}
+	if summary.Canceled != 3 || summary.Succeeded != 0 || summary.Failed != 0 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if len(reported) != 3 || !errors.Is(reported[0].Err, context.Canceled) {
+		t.Errorf("Expected a canceled result for every repository, got %+v", reported)
+	}
+}
+
+func TestSyncer_SyncContext_Timeout(t *testing.T) {
+	syncer := NewSyncer(1, NewConsoleReporter(&bytes.Buffer{}))
+	syncer.Timeout = time.Millisecond
+	syncer.sync = func(ctx context.Context, config CloneConfig) SyncResult {
+		<-ctx.Done()
+		return SyncResult{Err: ctx.Err()}
+	}
+
+	summary := syncer.SyncContext(context.Background(), testConfigs("slow"))
+
+	if summary.Failed != 1 || !errors.Is(summary.Results[0].Err, context.DeadlineExceeded) {
+		t.Errorf("Expected the repository to time out, got %+v", summary)
+	}
+}
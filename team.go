@@ -0,0 +1,170 @@
+package gitgrab
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Permission is a level of access to a repository, from pull (read) up to
+// admin
+type Permission string
+
+const (
+	PermissionPull     Permission = "pull"
+	PermissionTriage   Permission = "triage"
+	PermissionPush     Permission = "push"
+	PermissionMaintain Permission = "maintain"
+	PermissionAdmin    Permission = "admin"
+)
+
+var permissionRanks = map[Permission]int{
+	PermissionPull:     1,
+	PermissionTriage:   2,
+	PermissionPush:     3,
+	PermissionMaintain: 4,
+	PermissionAdmin:    5,
+}
+
+func (p Permission) String() string {
+	return string(p)
+}
+
+// AtLeast reports whether p grants at least the access of other. The empty
+// permission satisfies only the empty permission.
+func (p Permission) AtLeast(other Permission) bool {
+	return permissionRanks[p] >= permissionRanks[other]
+}
+
+// ParsePermission accepts the API's permission names as well as the read,
+// write and maintain aliases used in the GitHub UI
+func ParsePermission(s string) (Permission, error) {
+	switch strings.ToLower(s) {
+	case "pull", "read":
+		return PermissionPull, nil
+	case "triage":
+		return PermissionTriage, nil
+	case "push", "write":
+		return PermissionPush, nil
+	case "maintain":
+		return PermissionMaintain, nil
+	case "admin":
+		return PermissionAdmin, nil
+	default:
+		return "", fmt.Errorf("invalid permission: %s", s)
+	}
+}
+
+// RepositoryPermissions is the access the requesting user or team has to a
+// repository, as reported by the API
+type RepositoryPermissions struct {
+	Admin    bool `json:"admin"`
+	Maintain bool `json:"maintain"`
+	Push     bool `json:"push"`
+	Triage   bool `json:"triage"`
+	Pull     bool `json:"pull"`
+}
+
+// Highest returns the strongest permission granted
+func (p RepositoryPermissions) Highest() Permission {
+	switch {
+	case p.Admin:
+		return PermissionAdmin
+	case p.Maintain:
+		return PermissionMaintain
+	case p.Push:
+		return PermissionPush
+	case p.Triage:
+		return PermissionTriage
+	case p.Pull:
+		return PermissionPull
+	default:
+		return ""
+	}
+}
+
+// Team is the subset of a GitHub team that gitgrab needs
+type Team struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// TeamRepoOptions controls which of a team's repositories are listed
+type TeamRepoOptions struct {
+	// IncludeChildTeams also lists repositories of nested teams, recursively
+	IncludeChildTeams bool
+	// MinPermission drops repositories the team has weaker access to
+	MinPermission Permission
+}
+
+// FetchChildTeams lists the teams nested directly under a team
+func (gc *GitHubClient) FetchChildTeams(org OrganizationName, slug string) ([]Team, error) {
+	return gc.FetchChildTeamsContext(context.Background(), org, slug)
+}
+
+// FetchChildTeamsContext is like FetchChildTeams but stops when ctx is done
+func (gc *GitHubClient) FetchChildTeamsContext(ctx context.Context, org OrganizationName, slug string) ([]Team, error) {
+	endpoint := fmt.Sprintf("%s/orgs/%s/teams/%s/teams?per_page=%d", gc.baseURL, url.PathEscape(org.String()), url.PathEscape(slug), perPage)
+	return fetchPages[Team](ctx, gc, endpoint)
+}
+
+// FetchTeamRepos lists the repositories a team has access to. With child
+// teams included, a repository reachable through several teams is returned
+// once, carrying the strongest permission among them.
+func (gc *GitHubClient) FetchTeamRepos(org OrganizationName, slug string, opts TeamRepoOptions) ([]Repository, error) {
+	return gc.FetchTeamReposContext(context.Background(), org, slug, opts)
+}
+
+// FetchTeamReposContext is like FetchTeamRepos but stops when ctx is done
+func (gc *GitHubClient) FetchTeamReposContext(ctx context.Context, org OrganizationName, slug string, opts TeamRepoOptions) ([]Repository, error) {
+	slugs := []string{slug}
+	if opts.IncludeChildTeams {
+		seen := map[string]bool{slug: true}
+		for i := 0; i < len(slugs); i++ {
+			children, err := gc.FetchChildTeamsContext(ctx, org, slugs[i])
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				if !seen[child.Slug] {
+					seen[child.Slug] = true
+					slugs = append(slugs, child.Slug)
+				}
+			}
+		}
+	}
+
+	var repos []Repository
+	index := make(map[RepositoryName]int)
+	for _, s := range slugs {
+		endpoint := fmt.Sprintf("%s/orgs/%s/teams/%s/repos?per_page=%d", gc.baseURL, url.PathEscape(org.String()), url.PathEscape(s), perPage)
+		teamRepos, err := fetchPages[Repository](ctx, gc, endpoint)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range teamRepos {
+			if i, ok := index[repo.Name]; ok {
+				if repo.Permissions.Highest().AtLeast(repos[i].Permissions.Highest()) {
+					repos[i].Permissions = repo.Permissions
+				}
+				continue
+			}
+			index[repo.Name] = len(repos)
+			repos = append(repos, repo)
+		}
+	}
+
+	if opts.MinPermission == "" {
+		return repos, nil
+	}
+
+	var selected []Repository
+	for _, repo := range repos {
+		if repo.Permissions.Highest().AtLeast(opts.MinPermission) {
+			selected = append(selected, repo)
+		}
+	}
+	return selected, nil
+}
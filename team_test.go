@@ -0,0 +1,84 @@
+package gitgrab
+
+import "testing"
+
+func TestPermission_AtLeast(t *testing.T) {
+	if !PermissionAdmin.AtLeast(PermissionPush) {
+		t.Error("Expected admin to satisfy push")
+	}
+	if PermissionTriage.AtLeast(PermissionPush) {
+		t.Error("Expected triage not to satisfy push")
+	}
+	if !PermissionPull.AtLeast("") {
+		t.Error("Expected any permission to satisfy the empty permission")
+	}
+}
+
+func TestParsePermission(t *testing.T) {
+	tests := map[string]Permission{
+		"pull":     PermissionPull,
+		"read":     PermissionPull,
+		"Write":    PermissionPush,
+		"maintain": PermissionMaintain,
+		"ADMIN":    PermissionAdmin,
+	}
+	for input, expected := range tests {
+		actual, err := ParsePermission(input)
+		if err != nil || actual != expected {
+			t.Errorf("ParsePermission(%s) = %s, %v; expected %s", input, actual, err, expected)
+		}
+	}
+
+	if _, err := ParsePermission("owner"); err == nil {
+		t.Error("Expected error for invalid permission, got none")
+	}
+}
+
+func TestGitHubClient_FetchTeamRepos(t *testing.T) {
+	readOnly := RepositoryPermissions{Pull: true}
+	writer := RepositoryPermissions{Pull: true, Triage: true, Push: true}
+
+	routes := map[string]any{
+		"/orgs/acme/teams/platform/repos": []Repository{
+			{Name: "infra", Permissions: readOnly},
+			{Name: "docs", Permissions: readOnly},
+		},
+		"/orgs/acme/teams/platform/teams": []Team{{Slug: "sre"}},
+		"/orgs/acme/teams/sre/repos": []Repository{
+			{Name: "infra", Permissions: writer},
+			{Name: "runbooks", Permissions: writer},
+		},
+		"/orgs/acme/teams/sre/teams": []Team{},
+	}
+
+	tests := []struct {
+		name     string
+		opts     TeamRepoOptions
+		expected []RepositoryName
+	}{
+		{"team only", TeamRepoOptions{}, []RepositoryName{"infra", "docs"}},
+		{"with child teams", TeamRepoOptions{IncludeChildTeams: true}, []RepositoryName{"infra", "docs", "runbooks"}},
+		{"with child teams and push access", TeamRepoOptions{IncludeChildTeams: true, MinPermission: PermissionPush}, []RepositoryName{"infra", "runbooks"}},
+		{"push access without child teams", TeamRepoOptions{MinPermission: PermissionPush}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested []string
+			client := routedClient(t, routes, &requested)
+
+			repos, err := client.FetchTeamRepos(OrganizationName("acme"), "platform", tt.opts)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(repos) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %+v", tt.expected, repos)
+			}
+			for i, repo := range repos {
+				if repo.Name != tt.expected[i] {
+					t.Errorf("Expected %s at position %d, got %s", tt.expected[i], i, repo.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package gitgrab
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Strategies for updating a repository checked out on its tracked branch,
+// accepted for CloneConfig.UpdateStrategy
+const (
+	// UpdateFFOnly pulls only when the branch can be fast-forwarded and
+	// fails otherwise, so local history is never merged
+	UpdateFFOnly = "ff-only"
+	// UpdateRebase rebases local commits onto the pulled branch
+	UpdateRebase = "rebase"
+	// UpdateAutostash rebases like UpdateRebase, stashing local changes
+	// for the duration of the pull
+	UpdateAutostash = "autostash"
+	// UpdateSkipIfDirty fast-forwards like UpdateFFOnly, but leaves a
+	// repository with uncommitted changes alone
+	UpdateSkipIfDirty = "skip-if-dirty"
+)
+
+// ValidateUpdateStrategy checks an update strategy name, and that the git
+// backend can carry it out: go-git cannot rebase
+func ValidateUpdateStrategy(strategy, backend string) error {
+	switch strategy {
+	case "", UpdateFFOnly, UpdateSkipIfDirty:
+		return nil
+	case UpdateRebase, UpdateAutostash:
+		if backend == BackendGoGit {
+			return fmt.Errorf("update strategy %s is not supported by the %s backend", strategy, backend)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid update strategy: %s", strategy)
+}
+
+// pullArgs returns the git pull command for an update strategy
+func pullArgs(strategy string) []string {
+	switch strategy {
+	case UpdateRebase:
+		return []string{"pull", "--rebase"}
+	case UpdateAutostash:
+		return []string{"pull", "--rebase", "--autostash"}
+	default:
+		return []string{"pull", "--ff-only"}
+	}
+}
+
+// rebases reports whether a strategy can leave a rebase in progress
+func rebases(strategy string) bool {
+	return strategy == UpdateRebase || strategy == UpdateAutostash
+}
+
+// hasLocalChanges reports whether tracked files in the repository have
+// uncommitted changes. Untracked files are ignored, since a pull never
+// touches them unless upstream adds a file of the same name.
+func hasLocalChanges(ctx context.Context, git GitRunner, repoPath string) (bool, error) {
+	output, err := git.Run(ctx, repoPath, nil, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(output) != "", nil
+}
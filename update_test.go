@@ -0,0 +1,144 @@
+package gitgrab
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// divergedClone clones upstream and adds a commit on both sides, so the
+// clone can no longer be fast-forwarded
+func divergedClone(t *testing.T, strategy string) CloneConfig {
+	t.Helper()
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "Test User")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "test@example.com")
+	}
+
+	source := filepath.Join(t.TempDir(), "upstream")
+	initRepoOnBranch(t, source, "main")
+	config := CloneConfig{
+		Repository:     Repository{Name: "upstream", CloneURL: HTTPURL(source), DefaultBranch: "main"},
+		TargetDir:      t.TempDir(),
+		Method:         CloneMethodHTTP,
+		Reporter:       ReporterFunc(func(Event) {}),
+		UpdateStrategy: strategy,
+	}
+	if result := SyncRepo(config); result.Err != nil {
+		t.Fatalf("Unexpected error cloning: %v", result.Err)
+	}
+
+	exec.Command("git", "-C", source, "commit", "--allow-empty", "-m", "Upstream commit").Run()
+	exec.Command("git", "-C", config.RepoPath(), "commit", "--allow-empty", "-m", "Local commit").Run()
+	return config
+}
+
+func revParse(t *testing.T, dir, rev string) string {
+	t.Helper()
+	output, err := exec.Command("git", "-C", dir, "rev-parse", rev).Output()
+	if err != nil {
+		t.Fatalf("Failed to resolve %s: %v", rev, err)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func TestSyncRepo_FastForwardOnlyRefusesDivergedHistory(t *testing.T) {
+	config := divergedClone(t, UpdateFFOnly)
+	before := revParse(t, config.RepoPath(), "HEAD")
+
+	result := SyncRepo(config)
+
+	if result.Err == nil {
+		t.Fatal("Expected diverged history to fail the pull")
+	}
+	if after := revParse(t, config.RepoPath(), "HEAD"); after != before {
+		t.Errorf("Expected HEAD to stay at %s, got %s", before, after)
+	}
+}
+
+func TestSyncRepo_RebaseReplaysLocalCommits(t *testing.T) {
+	config := divergedClone(t, UpdateRebase)
+
+	result := SyncRepo(config)
+
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+	if parent := revParse(t, config.RepoPath(), "HEAD^"); parent != revParse(t, config.RepoPath(), "origin/main") {
+		t.Errorf("Expected the local commit on top of origin/main, got parent %s", parent)
+	}
+}
+
+func TestSyncRepo_SkipIfDirty(t *testing.T) {
+	for _, runner := range []GitRunner{ExecGitRunner{}, GoGitRunner{}} {
+		config := divergedClone(t, UpdateSkipIfDirty)
+		config.Git = runner
+		tracked := filepath.Join(config.RepoPath(), "tracked.txt")
+		os.WriteFile(tracked, []byte("one\n"), 0644)
+		exec.Command("git", "-C", config.RepoPath(), "add", "tracked.txt").Run()
+		exec.Command("git", "-C", config.RepoPath(), "commit", "-m", "Add tracked file").Run()
+
+		// Untracked files don't count as local changes
+		os.WriteFile(filepath.Join(config.RepoPath(), "scratch.txt"), []byte("notes\n"), 0644)
+		if dirty, err := hasLocalChanges(t.Context(), runner, config.RepoPath()); err != nil || dirty {
+			t.Errorf("%T: expected untracked files to be ignored, got %v, %v", runner, dirty, err)
+		}
+
+		os.WriteFile(tracked, []byte("two\n"), 0644)
+		result := SyncRepo(config)
+
+		if result.Err != nil || result.Action != ActionSkip || !strings.Contains(result.Reason, "uncommitted changes") {
+			t.Errorf("%T: expected repository with local changes to be skipped, got %+v", runner, result)
+		}
+	}
+}
+
+func TestPlanRepo_SkipIfDirtyLeavesIndexAlone(t *testing.T) {
+	config := divergedClone(t, UpdateSkipIfDirty)
+	tracked := filepath.Join(config.RepoPath(), "tracked.txt")
+	os.WriteFile(tracked, []byte("one\n"), 0644)
+	exec.Command("git", "-C", config.RepoPath(), "add", "tracked.txt").Run()
+	exec.Command("git", "-C", config.RepoPath(), "commit", "-m", "Add tracked file").Run()
+
+	// A new timestamp on an unchanged file makes git status refresh the
+	// index, unless optional locks are off
+	index := filepath.Join(config.RepoPath(), ".git", "index")
+	before, _ := os.ReadFile(index)
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(tracked, later, later)
+
+	if plan := PlanRepo(config); plan.Action != ActionPull {
+		t.Fatalf("Expected a clean repository to be pulled, got %+v", plan)
+	}
+	if after, _ := os.ReadFile(index); !bytes.Equal(before, after) {
+		t.Error("Expected planning to leave the index unchanged")
+	}
+}
+
+func TestValidateUpdateStrategy(t *testing.T) {
+	tests := []struct {
+		strategy string
+		backend  string
+		valid    bool
+	}{
+		{"", BackendGoGit, true},
+		{UpdateFFOnly, BackendGoGit, true},
+		{UpdateSkipIfDirty, BackendGoGit, true},
+		{UpdateRebase, BackendGit, true},
+		{UpdateAutostash, "", true},
+		{UpdateRebase, BackendGoGit, false},
+		{UpdateAutostash, BackendGoGit, false},
+		{"merge", BackendGit, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateUpdateStrategy(tt.strategy, tt.backend); (err == nil) != tt.valid {
+			t.Errorf("Expected %q with backend %q to be valid: %v, got %v", tt.strategy, tt.backend, tt.valid, err)
+		}
+	}
+}